@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultReadyQueueThreshold = 0.9
+
+var startTime = time.Now()
+
+type statusResponse struct {
+	State      string            `json:"state"`
+	Paused     bool              `json:"paused"`
+	Uptime     string            `json:"uptime"`
+	Config     config            `json:"config"`
+	Queue      statusQueue       `json:"queue"`
+	Deliveries statusDeliveries  `json:"deliveries"`
+	Rates      statusRates       `json:"rates"`
+	Redactions map[string]uint64 `json:"redactions,omitempty"` // messages redacted by every rule
+	Digest     *statusDigest     `json:"digest,omitempty"`
+	LastError  *statusError      `json:"last_error,omitempty"`
+}
+
+type statusQueue struct {
+	Stdin           int    `json:"stdin"`
+	StdinCapacity   int    `json:"stdin_capacity"`
+	Message         int    `json:"message"`
+	MessageCapacity int    `json:"message_capacity"`
+	Spilled         int    `json:"spilled"`
+	Rejected        uint64 `json:"rejected"`
+	Dropped         uint64 `json:"dropped"`  // syslog UDP messages dropped because the Stdin Channel was full
+	Filtered        uint64 `json:"filtered"` // messages dropped by the pipeline
+}
+
+type statusDeliveries struct {
+	Enqueued uint64 `json:"enqueued"`
+	Retried  uint64 `json:"retried"`
+	Sent     uint64 `json:"sent"`
+	Failed   uint64 `json:"failed"`
+	Expired  uint64 `json:"expired"`
+	InFlight int    `json:"in_flight"`
+	Dead     int    `json:"dead_letters"`
+}
+
+type statusRates struct {
+	MaxPerSecond    float64 `json:"max_per_second"`    // limit given by --messages and --interval, or by rate.messages_per_second in stream mode
+	SentPerSecond   float64 `json:"sent_per_second"`   // average since the start
+	FailedPerSecond float64 `json:"failed_per_second"` // average since the start
+}
+
+type statusDigest struct {
+	Groups    int    `json:"groups"`    // groups of the current window
+	Pending   int    `json:"pending"`   // messages of the current window
+	Digested  uint64 `json:"digested"`  // messages grouped since the start
+	Summaries uint64 `json:"summaries"` // summaries queued since the start
+}
+
+// maxPerSecond returns the limit of the rate of messages of the batch mode
+func maxPerSecond(c config) float64 {
+	if c.Batch.Mode == "stream" {
+		return float64(c.Rate.MessagesPerSecond)
+	}
+	return float64(c.Rate.MessagesPerInterval) / c.Rate.Interval.Seconds()
+}
+
+type statusError struct {
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+type rateRequest struct {
+	MessagesPerSecond int `json:"messages_per_second"`
+}
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+type cancelRequest struct {
+	GUID    string `json:"guid"`
+	Indexes []int  `json:"indexes"`
+}
+
+type countResponse struct {
+	Count int `json:"count"`
+}
+
+type deadLetterResponse struct {
+	GUID        string `json:"guid"`
+	Index       int    `json:"index"`
+	NumRetrials int    `json:"retrials"`
+	Error       string `json:"error"`
+	Body        string `json:"body"`
+}
+
+// initAdmin serves the health, readiness and status endpoints on addr,
+// plus the control endpoints under /admin/ when a token is provided
+func initAdmin(addr string, readyQueueThreshold float64, token string) {
+	handler := adminHandler(readyQueueThreshold, token)
+	go func() {
+		log.Infof("serving admin endpoints on %s", addr)
+		if err := http.ListenAndServe(addr, handler); err != nil {
+			log.Errorf("admin server stopped: %v", err)
+		}
+	}()
+}
+
+// adminHandler routes the health, readiness, status and control endpoints
+func adminHandler(readyQueueThreshold float64, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", handleHealth)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReady(w, r, readyQueueThreshold)
+	})
+	mux.HandleFunc("/status", handleStatus)
+
+	mux.Handle("/admin/pause", control(token, http.MethodPost, handlePause))
+	mux.Handle("/admin/resume", control(token, http.MethodPost, handleResume))
+	mux.Handle("/admin/rate", control(token, http.MethodPut, handleRate))
+	mux.Handle("/admin/loglevel", control(token, http.MethodPut, handleLogLevel))
+	mux.Handle("/admin/flush", control(token, http.MethodPost, handleFlush))
+	mux.Handle("/admin/deadletters", control(token, http.MethodGet, handleDeadLetters))
+	mux.Handle("/admin/deadletters/replay", control(token, http.MethodPost, handleReplay))
+	mux.Handle("/admin/cancel", control(token, http.MethodPost, handleCancel))
+	return mux
+}
+
+// handleHealth reports that the process is alive
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReady reports whether notify is able to deliver new notifications: the listener is running
+// and the Message Channel is below the threshold (fraction of its capacity). An unbuffered Message Channel
+// has no depth to compare with the threshold. notilib has no circuit breaker, so there is no open circuit to report.
+func handleReady(w http.ResponseWriter, r *http.Request, threshold float64) {
+	stats := notilib.Stats()
+
+	reason := ""
+	switch {
+	case isTerminating.Load():
+		reason = "terminating"
+	case stats.State != "listening":
+		reason = fmt.Sprintf("listener is %s", stats.State)
+	case stats.Paused:
+		reason = "delivery paused"
+	case stats.QueueCapacity > 0 && float64(stats.QueueLength) >= threshold*float64(stats.QueueCapacity):
+		reason = fmt.Sprintf("queue above threshold: %d/%d", stats.QueueLength, stats.QueueCapacity)
+	}
+
+	if reason != "" {
+		http.Error(w, reason, http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// handleStatus returns a JSON snapshot of the configuration, the queues and the deliveries
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	stats := notilib.Stats()
+	uptime := time.Since(startTime)
+
+	// the secrets are masked and the log level may have been changed at runtime
+	cfg := conf.Load().masked()
+	cfg.Log.Level = log.GetLevel().String()
+
+	res := statusResponse{
+		State:  stats.State,
+		Paused: stats.Paused,
+		Uptime: uptime.Round(time.Second).String(),
+		Config: cfg,
+		Queue: statusQueue{
+			Stdin:           len(stdinChan),
+			StdinCapacity:   cap(stdinChan),
+			Message:         stats.QueueLength,
+			MessageCapacity: stats.QueueCapacity,
+			Spilled:         stats.Spilled,
+			Rejected:        inputRejects.rejected(),
+			Dropped:         atomic.LoadUint64(&ingestDropped),
+			Filtered:        atomic.LoadUint64(&pipelineDropped),
+		},
+		Deliveries: statusDeliveries{
+			Enqueued: stats.Enqueued,
+			Retried:  stats.Retried,
+			Sent:     stats.Sent,
+			Failed:   stats.Failed,
+			Expired:  stats.Expired,
+			InFlight: stats.InFlight,
+			Dead:     stats.DeadLetters,
+		},
+		Rates: statusRates{
+			MaxPerSecond:    maxPerSecond(cfg),
+			SentPerSecond:   float64(stats.Sent) / uptime.Seconds(),
+			FailedPerSecond: float64(stats.Failed) / uptime.Seconds(),
+		},
+	}
+	if redactor != nil {
+		res.Redactions = redactor.Counts()
+	}
+	if inputDigest != nil {
+		groups, pending := inputDigest.pending()
+		res.Digest = &statusDigest{
+			Groups:    groups,
+			Pending:   pending,
+			Digested:  atomic.LoadUint64(&inputDigest.digested),
+			Summaries: atomic.LoadUint64(&inputDigest.summaries),
+		}
+	}
+	if stats.LastError != "" {
+		res.LastError = &statusError{Message: stats.LastError, Time: stats.LastErrorAt}
+	}
+
+	writeJSON(w, res)
+}
+
+// control guards a control endpoint: it requires the method and the token in the header "Authorization: Bearer <token>".
+// Without a configured token the control endpoints are disabled.
+func control(token, method string, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "admin token not configured", http.StatusForbidden)
+			return
+		}
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			http.Error(w, "invalid admin token", http.StatusUnauthorized)
+			return
+		}
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	})
+}
+
+func handlePause(w http.ResponseWriter, r *http.Request) {
+	if err := notilib.Pause(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	handleStatus(w, r)
+}
+
+func handleResume(w http.ResponseWriter, r *http.Request) {
+	if err := notilib.Resume(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	handleStatus(w, r)
+}
+
+func handleRate(w http.ResponseWriter, r *http.Request) {
+	var req rateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := notilib.SetRate(req.MessagesPerSecond); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, req)
+}
+
+func handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	level, err := log.ParseLevel(req.Level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := notilib.SetLogLevel(level); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, req)
+}
+
+func handleFlush(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), conf.Load().Timeout.Duration)
+	defer cancel()
+	n, err := notilib.Flush(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, countResponse{Count: n})
+}
+
+func handleDeadLetters(w http.ResponseWriter, r *http.Request) {
+	letters := []deadLetterResponse{}
+	for _, e := range notilib.DeadLetters() {
+		letters = append(letters, newDeadLetterResponse(e))
+	}
+	writeJSON(w, letters)
+}
+
+func handleReplay(w http.ResponseWriter, r *http.Request) {
+	n, err := notilib.ReplayDeadLetters()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	inputOutcomes.replayed(n)
+	writeJSON(w, countResponse{Count: n})
+}
+
+func handleCancel(w http.ResponseWriter, r *http.Request) {
+	var req cancelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+	res, err := notilib.Cancel(req.GUID, req.Indexes...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	inputOutcomes.addCancelled(res.Cancelled)
+	writeJSON(w, res)
+}
+
+func newDeadLetterResponse(e nl.NError) deadLetterResponse {
+	return deadLetterResponse{
+		GUID:        e.GUID,
+		Index:       e.Index,
+		NumRetrials: e.NumRetrials,
+		Error:       e.ErrorMessage,
+		Body:        e.Content, // redacted, as it was sent
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Errorf("unable to encode the response: %v", err)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+const testAdminToken = "secret"
+
+// startTestNotilib replaces notilib with a paused instance delivering to the receiver, or to one that accepts
+// everything if nil, and resets the configuration and the outcomes
+func startTestNotilib(t *testing.T, receiver http.HandlerFunc) *config {
+	t.Helper()
+	if receiver == nil {
+		receiver = func(w http.ResponseWriter, r *http.Request) {}
+	}
+	server := httptest.NewServer(receiver)
+	t.Cleanup(server.Close)
+
+	c := defaultConfig()
+	c.Endpoint.URL = server.URL
+	config := c.notilibConfig()
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	config.Logger = nl.NewLogrusLogger(logger)
+
+	n, err := nl.New(c.Endpoint.URL, c.httpClient(), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := n.Pause(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := n.Listen(t.Context()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	previous := notilib
+	notilib = n
+	conf.Store(c)
+	inputRejects, _ = newRejects("")
+	inputOutcomes = outcomes{}
+	t.Cleanup(func() {
+		if quit, err := n.Terminate(time.Second); err == nil {
+			<-quit
+		}
+		notilib = previous
+	})
+	return c
+}
+
+// adminRequest sends a request with the admin token to the admin endpoints
+func adminRequest(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	rec := httptest.NewRecorder()
+	adminHandler(defaultReadyQueueThreshold, testAdminToken).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestControl(t *testing.T) {
+	tt := []struct {
+		name          string
+		token         string
+		authorization string
+		method        string
+		status        int
+		response      string
+	}{
+		{"authorized", testAdminToken, "Bearer " + testAdminToken, http.MethodPost, http.StatusOK, "done"},
+		{"token not configured", "", "Bearer ", http.MethodPost, http.StatusForbidden, "admin token not configured"},
+		{"missing token", testAdminToken, "", http.MethodPost, http.StatusUnauthorized, "invalid admin token"},
+		{"wrong token", testAdminToken, "Bearer other", http.MethodPost, http.StatusUnauthorized, "invalid admin token"},
+		{"wrong token checked before the method", testAdminToken, "Bearer other", http.MethodGet, http.StatusUnauthorized, "invalid admin token"},
+		{"wrong method", testAdminToken, "Bearer " + testAdminToken, http.MethodGet, http.StatusMethodNotAllowed, "method not allowed"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := control(tc.token, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("done"))
+			})
+			req := httptest.NewRequest(tc.method, "/admin/pause", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d; got %d", tc.status, rec.Code)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tc.response {
+				t.Errorf("expected response %q; got %q", tc.response, got)
+			}
+			if tc.status == http.StatusMethodNotAllowed && rec.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("expected Allow: POST; got %q", rec.Header().Get("Allow"))
+			}
+		})
+	}
+}
+
+// statsNotilib reports fixed stats
+type statsNotilib struct {
+	nl.Notilib
+	stats nl.Stats
+}
+
+func (n statsNotilib) Stats() nl.Stats {
+	return n.stats
+}
+
+func TestHandleReady(t *testing.T) {
+	tt := []struct {
+		name        string
+		stats       nl.Stats
+		terminating bool
+		status      int
+		response    string
+	}{
+		{"ready", nl.Stats{State: "listening", QueueLength: 8, QueueCapacity: 10}, false, http.StatusOK, "ok"},
+		{"unbuffered Message Channel", nl.Stats{State: "listening"}, false, http.StatusOK, "ok"},
+		{"queue above threshold", nl.Stats{State: "listening", QueueLength: 9, QueueCapacity: 10}, false, http.StatusServiceUnavailable, "queue above threshold: 9/10"},
+		{"paused", nl.Stats{State: "listening", Paused: true, QueueCapacity: 10}, false, http.StatusServiceUnavailable, "delivery paused"},
+		{"not listening", nl.Stats{State: "idle", QueueCapacity: 10}, false, http.StatusServiceUnavailable, "listener is idle"},
+		{"terminating", nl.Stats{State: "listening", QueueCapacity: 10}, true, http.StatusServiceUnavailable, "terminating"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			previous := notilib
+			notilib = statsNotilib{stats: tc.stats}
+			defer func() { notilib = previous }()
+			isTerminating.Store(tc.terminating)
+			defer isTerminating.Store(false)
+
+			rec := httptest.NewRecorder()
+			handleReady(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil), 0.9)
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d; got %d", tc.status, rec.Code)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tc.response {
+				t.Errorf("expected response %q; got %q", tc.response, got)
+			}
+		})
+	}
+}
+
+func TestAdminPauseResume(t *testing.T) {
+	startTestNotilib(t, nil)
+
+	tt := []struct {
+		name   string
+		path   string
+		paused bool
+	}{
+		{"resume", "/admin/resume", false},
+		{"pause", "/admin/pause", true},
+		{"pause twice", "/admin/pause", true},
+		{"resume again", "/admin/resume", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := adminRequest(http.MethodPost, tc.path, "")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d; got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+			var res statusResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatalf("unexpected response %q: %v", rec.Body.String(), err)
+			}
+			if res.Paused != tc.paused || notilib.Stats().Paused != tc.paused {
+				t.Errorf("expected paused %v; got %v in the status and %v in notilib", tc.paused, res.Paused, notilib.Stats().Paused)
+			}
+		})
+	}
+
+	if rec := adminRequest(http.MethodGet, "/admin/pause", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d; got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+}
+
+func TestAdminRate(t *testing.T) {
+	startTestNotilib(t, nil)
+
+	tt := []struct {
+		name     string
+		method   string
+		body     string
+		status   int
+		response string
+	}{
+		{"valid", http.MethodPut, `{"messages_per_second": 50}`, http.StatusOK, `{"messages_per_second":50}`},
+		{"highest", http.MethodPut, `{"messages_per_second": 1000000000}`, http.StatusOK, `{"messages_per_second":1000000000}`},
+		{"zero", http.MethodPut, `{"messages_per_second": 0}`, http.StatusBadRequest, "invalid rate: 0, it must be between 1 and 1000000000"},
+		{"negative", http.MethodPut, `{"messages_per_second": -1}`, http.StatusBadRequest, "invalid rate: -1, it must be between 1 and 1000000000"},
+		{"too high", http.MethodPut, `{"messages_per_second": 1000000001}`, http.StatusBadRequest, "invalid rate: 1000000001, it must be between 1 and 1000000000"},
+		{"invalid request", http.MethodPut, `{"messages_per_second": "fast"}`, http.StatusBadRequest, "invalid request: json: cannot unmarshal string into Go struct field rateRequest.messages_per_second of type int"},
+		{"wrong method", http.MethodPost, `{"messages_per_second": 50}`, http.StatusMethodNotAllowed, "method not allowed"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := adminRequest(tc.method, "/admin/rate", tc.body)
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d; got %d", tc.status, rec.Code)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tc.response {
+				t.Errorf("expected response %q; got %q", tc.response, got)
+			}
+		})
+	}
+}
+
+func TestAdminCancel(t *testing.T) {
+	startTestNotilib(t, nil)
+	guid, err := notilib.Notify([]string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	inputOutcomes.addQueued(3)
+
+	rec := adminRequest(http.MethodPost, "/admin/cancel", `{"guid": "`+guid+`", "indexes": [0, 2]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	var res nl.CancelResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatalf("unexpected response %q: %v", rec.Body.String(), err)
+	}
+	if expected := (nl.CancelResult{GUID: guid, Cancelled: 2}); res != expected {
+		t.Errorf("expected result %+v; got %+v", expected, res)
+	}
+
+	// cancelling again does not count the messages twice
+	if rec := adminRequest(http.MethodPost, "/admin/cancel", `{"guid": "`+guid+`", "indexes": [0]}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	rec = adminRequest(http.MethodPost, "/admin/cancel", `{"guid": "unknown"}`)
+	if rec.Code != http.StatusNotFound || strings.TrimSpace(rec.Body.String()) != "unknown GUID: unknown" {
+		t.Errorf("expected status %d and error %q; got %d and %q", http.StatusNotFound, "unknown GUID: unknown", rec.Code, rec.Body.String())
+	}
+	rec = adminRequest(http.MethodPost, "/admin/cancel", `{"guid": 1}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d; got %d", http.StatusBadRequest, rec.Code)
+	}
+
+	summary := inputOutcomes.summary()
+	if summary.Cancelled != 2 || summary.Pending != 1 {
+		t.Errorf("expected 2 messages cancelled and 1 pending; got %v", summary)
+	}
+}
+
+func TestAdminDeadLetters(t *testing.T) {
+	startTestNotilib(t, nil)
+	notilib.DeadLetter(nl.NError{
+		ErrorMessage: "unexpected HTTP Status: 500 Internal Server Error",
+		Content:      "disk full on [EMAIL]",
+		Notification: nl.Notification{Body: []byte("disk full on bob@example.com")},
+		NumRetrials:  2,
+		GUID:         "3b9f7a2e",
+		Index:        1,
+	})
+	inputOutcomes.addQueued(1)
+	inputOutcomes.addFailed(1)
+
+	rec := adminRequest(http.MethodGet, "/admin/deadletters", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+	}
+	expected := `[{"guid":"3b9f7a2e","index":1,"retrials":2,"error":"unexpected HTTP Status: 500 Internal Server Error","body":"disk full on [EMAIL]"}]`
+	if got := strings.TrimSpace(rec.Body.String()); got != expected {
+		t.Errorf("expected dead letters %s; got %s", expected, got)
+	}
+
+	rec = adminRequest(http.MethodPost, "/admin/deadletters/replay", "")
+	if got := strings.TrimSpace(rec.Body.String()); rec.Code != http.StatusOK || got != `{"count":1}` {
+		t.Fatalf("expected status %d and count 1; got %d and %s", http.StatusOK, rec.Code, got)
+	}
+	// the replayed message is pending again, the dead letter queue is empty
+	if summary := inputOutcomes.summary(); summary.Failed != 0 || summary.Pending != 1 {
+		t.Errorf("expected 0 messages failed and 1 pending; got %v", summary)
+	}
+	rec = adminRequest(http.MethodGet, "/admin/deadletters", "")
+	if got := strings.TrimSpace(rec.Body.String()); got != "[]" {
+		t.Errorf("expected no dead letters; got %s", got)
+	}
+}
@@ -0,0 +1,576 @@
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// envPrefix is the prefix of the environment variables overriding the configuration,
+// e.g. NOTIFY_ENDPOINT_URL overrides endpoint.url
+const envPrefix = "NOTIFY_"
+
+// configFileEnv is the environment variable with the path of the configuration file when --config is not provided
+const configFileEnv = envPrefix + "CONFIG"
+
+// maskedSecret replaces the secrets when printing the configuration
+const maskedSecret = "******"
+
+// config is the configuration of notify. It is merged from, in order of precedence:
+// the default values, the configuration file, the NOTIFY_* environment variables and the flags.
+type config struct {
+	Input    inputConfig    `yaml:"input" toml:"input" json:"input"`
+	Ingest   ingestConfig   `yaml:"ingest" toml:"ingest" json:"ingest"`
+	Pipeline []stageConfig  `yaml:"pipeline" toml:"pipeline" json:"pipeline"` // processors applied to the messages before queueing them
+	Redact   redactConfig   `yaml:"redact" toml:"redact" json:"redact"`
+	Digest   digestConfig   `yaml:"digest" toml:"digest" json:"digest"`
+	Endpoint endpointConfig `yaml:"endpoint" toml:"endpoint" json:"endpoint"`
+	Auth     authConfig     `yaml:"auth" toml:"auth" json:"auth"`
+	Retry    retryConfig    `yaml:"retry" toml:"retry" json:"retry"`
+	Rate     rateConfig     `yaml:"rate" toml:"rate" json:"rate"`
+	Batch    batchConfig    `yaml:"batch" toml:"batch" json:"batch"`
+	Queue    queueConfig    `yaml:"queue" toml:"queue" json:"queue"`
+	Log      logConfig      `yaml:"log" toml:"log" json:"log"`
+	Metrics  metricsConfig  `yaml:"metrics" toml:"metrics" json:"metrics"`
+	Admin    adminConfig    `yaml:"admin" toml:"admin" json:"admin"`
+	Timeout  duration       `yaml:"timeout" toml:"timeout" json:"timeout"` // timeout for flushing the channels on terminate
+}
+
+type inputConfig struct {
+	Format           string   `yaml:"format" toml:"format" json:"format"`                                  // text or jsonl
+	Rejects          string   `yaml:"rejects" toml:"rejects" json:"rejects"`                               // file where the malformed messages are written
+	Framing          string   `yaml:"framing" toml:"framing" json:"framing"`                               // lines, delimiter, nul, length or multiline
+	Delimiter        string   `yaml:"delimiter" toml:"delimiter" json:"delimiter"`                         // delimiter of the messages with the delimiter framing
+	MultilinePattern string   `yaml:"multiline_pattern" toml:"multiline_pattern" json:"multiline_pattern"` // continuation lines with the multiline framing
+	MaxMessageSize   int      `yaml:"max_message_size" toml:"max_message_size" json:"max_message_size"`    // in bytes
+	Oversize         string   `yaml:"oversize" toml:"oversize" json:"oversize"`                            // truncate or reject the bigger messages
+	Tail             []string `yaml:"tail" toml:"tail" json:"tail"`                                        // glob patterns of the files followed instead of stdin
+	TailState        string   `yaml:"tail_state" toml:"tail_state" json:"tail_state"`                      // file where the offsets of the followed files are saved
+	TailPoll         duration `yaml:"tail_poll" toml:"tail_poll" json:"tail_poll"`                         // interval for checking the followed files
+}
+
+// ingestConfig are the addresses of the network inputs, an empty address disables the input
+type ingestConfig struct {
+	HTTP      string `yaml:"http" toml:"http" json:"http"`                   // HTTP endpoint POST /messages
+	TCP       string `yaml:"tcp" toml:"tcp" json:"tcp"`                      // TCP connections read with the input format and framing
+	SyslogUDP string `yaml:"syslog_udp" toml:"syslog_udp" json:"syslog_udp"` // RFC 5424 messages, one per datagram
+	SyslogTCP string `yaml:"syslog_tcp" toml:"syslog_tcp" json:"syslog_tcp"` // RFC 5424 messages framed as RFC 6587
+	Unix      string `yaml:"unix" toml:"unix" json:"unix"`                   // path of a unix socket read with the input format and framing
+	UnixAck   string `yaml:"unix_ack" toml:"unix_ack" json:"unix_ack"`       // response awaited by the unix socket clients: queued or delivered
+}
+
+// enabled reports whether any network input is configured
+func (c ingestConfig) enabled() bool {
+	return c.HTTP != "" || c.TCP != "" || c.SyslogUDP != "" || c.SyslogTCP != "" || c.Unix != ""
+}
+
+type endpointConfig struct {
+	URL     string            `yaml:"url" toml:"url" json:"url"`
+	Method  string            `yaml:"method" toml:"method" json:"method"`
+	Timeout duration          `yaml:"timeout" toml:"timeout" json:"timeout"` // timeout of every request, 0 means no timeout
+	Headers map[string]string `yaml:"headers" toml:"headers" json:"headers"`
+}
+
+type authConfig struct {
+	Type     string `yaml:"type" toml:"type" json:"type"` // none, bearer or basic
+	Token    string `yaml:"token" toml:"token" json:"token"`
+	Username string `yaml:"username" toml:"username" json:"username"`
+	Password string `yaml:"password" toml:"password" json:"password"`
+}
+
+type retryConfig struct {
+	MaxRetrials        int `yaml:"max_retrials" toml:"max_retrials" json:"max_retrials"`
+	DeadLetterCapacity int `yaml:"dead_letter_capacity" toml:"dead_letter_capacity" json:"dead_letter_capacity"`
+}
+
+type rateConfig struct {
+	Interval            duration `yaml:"interval" toml:"interval" json:"interval"`
+	MessagesPerInterval int      `yaml:"messages_per_interval" toml:"messages_per_interval" json:"messages_per_interval"`
+	MessagesPerSecond   int      `yaml:"messages_per_second" toml:"messages_per_second" json:"messages_per_second"`
+	Burst               int      `yaml:"burst" toml:"burst" json:"burst"`
+}
+
+type queueConfig struct {
+	StdinCapacity   int    `yaml:"stdin_capacity" toml:"stdin_capacity" json:"stdin_capacity"`
+	MessageCapacity int    `yaml:"message_capacity" toml:"message_capacity" json:"message_capacity"`
+	ErrorCapacity   int    `yaml:"error_capacity" toml:"error_capacity" json:"error_capacity"`
+	Overflow        string `yaml:"overflow" toml:"overflow" json:"overflow"`
+	PausedOverflow  string `yaml:"paused_overflow" toml:"paused_overflow" json:"paused_overflow"`
+	SpillDir        string `yaml:"spill_dir" toml:"spill_dir" json:"spill_dir"`
+}
+
+type logConfig struct {
+	Level string `yaml:"level" toml:"level" json:"level"`
+}
+
+type metricsConfig struct {
+	Addr string `yaml:"addr" toml:"addr" json:"addr"`
+}
+
+type adminConfig struct {
+	Addr                string  `yaml:"addr" toml:"addr" json:"addr"`
+	Token               string  `yaml:"token" toml:"token" json:"token"`
+	ReadyQueueThreshold float64 `yaml:"ready_queue_threshold" toml:"ready_queue_threshold" json:"ready_queue_threshold"`
+}
+
+// duration is a time.Duration written as text in the configuration file, e.g. "5s"
+type duration struct {
+	time.Duration
+}
+
+func (d *duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", text)
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Set allows to use a duration as a flag value
+func (d *duration) Set(value string) error {
+	return d.UnmarshalText([]byte(value))
+}
+
+// stringList is a repeatable flag, its first occurrence replaces the values of the configuration
+type stringList struct {
+	values *[]string
+	set    bool
+}
+
+func (l *stringList) String() string {
+	if l.values == nil {
+		return ""
+	}
+	return strings.Join(*l.values, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	if !l.set {
+		*l.values = nil
+		l.set = true
+	}
+	*l.values = append(*l.values, value)
+	return nil
+}
+
+func defaultConfig() *config {
+	return &config{
+		Input: inputConfig{
+			Format:           defaultInputFormat,
+			Framing:          defaultFraming,
+			MultilinePattern: defaultMultilinePattern,
+			MaxMessageSize:   defaultMaxMessageSize,
+			Oversize:         defaultOversize,
+			TailPoll:         duration{defaultTailPoll},
+		},
+		Ingest:   ingestConfig{UnixAck: defaultUnixAck},
+		Redact:   redactConfig{Mode: defaultRedactMode},
+		Digest:   digestConfig{Key: defaultDigestKey, Format: defaultDigestFormat, MaxGroups: defaultDigestMaxGroups},
+		Endpoint: endpointConfig{Method: defaultMethod},
+		Auth:     authConfig{Type: "none"},
+		Retry: retryConfig{
+			MaxRetrials:        defaultMaxNumRetrials,
+			DeadLetterCapacity: defaultDeadLetterCapacity,
+		},
+		Rate: rateConfig{
+			Interval:            duration{defaultInterval},
+			MessagesPerInterval: defaultMaxNumMessagesToProcess,
+			MessagesPerSecond:   defaultMessagesPerSecond,
+			Burst:               defaultBurst,
+		},
+		Batch: batchConfig{Mode: defaultBatchMode, Size: defaultBatchSize},
+		Queue: queueConfig{
+			StdinCapacity:   defaultChannelCapacity,
+			MessageCapacity: defaultMessageCapacity,
+			ErrorCapacity:   defaultErrorCapacity,
+			Overflow:        defaultOverflow.String(),
+			PausedOverflow:  defaultPausedOverflow.String(),
+		},
+		Log:     logConfig{Level: defaultLogLevel.String()},
+		Admin:   adminConfig{ReadyQueueThreshold: defaultReadyQueueThreshold},
+		Timeout: duration{defaultTimeout},
+	}
+}
+
+// loadFile decodes the configuration file over c, the format is chosen from the extension (.yaml, .yml, .toml or .json).
+// Unknown keys are rejected.
+func (c *config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read the configuration file: %v", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown field %q", path, undecoded[0].String())
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			return fmt.Errorf("%s: %v", path, jsonError(data, err))
+		}
+	default:
+		return fmt.Errorf("%s: unsupported configuration format %q, valid extensions: .yaml, .yml, .toml, .json", path, ext)
+	}
+	return nil
+}
+
+// jsonError adds the line and column to the errors of the JSON decoder that report an offset
+func jsonError(data []byte, err error) error {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return err
+	}
+	line := 1 + bytes.Count(data[:offset], []byte("\n"))
+	column := offset - int64(bytes.LastIndexByte(data[:offset], '\n'))
+	return fmt.Errorf("line %d, column %d: %v", line, column, err)
+}
+
+// applyEnv overrides the fields of c with the NOTIFY_* variables of environ (formatted as "KEY=value").
+// The name of a variable is the path of the field in upper case, e.g. NOTIFY_RATE_MESSAGES_PER_SECOND.
+func (c *config) applyEnv(environ []string) error {
+	vars := map[string]string{}
+	for _, kv := range environ {
+		if key, value, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(key, envPrefix) && key != configFileEnv {
+			vars[key] = value
+		}
+	}
+
+	var errs []string
+	walkFields(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.Value) {
+		name := envPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+		value, ok := vars[name]
+		if !ok {
+			return
+		}
+		delete(vars, name)
+		if err := setField(field, value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	})
+	for name := range vars {
+		errs = append(errs, fmt.Sprintf("%s: unknown variable", name))
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// walkFields calls f for every leaf field of v with its path made of the yaml names, e.g. "endpoint.url"
+func walkFields(v reflect.Value, prefix string, f func(path string, field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		path := prefix + name
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct && !field.Addr().Type().Implements(textUnmarshalerType) {
+			walkFields(field, path+".", f)
+			continue
+		}
+		f(path, field)
+	}
+}
+
+// setField parses the text value into the field
+func setField(field reflect.Value, value string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(value))
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(v))
+	case reflect.Float64:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		field.SetFloat(v)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s, it can only be set in the configuration file", field.Type())
+		}
+		// lists are written as comma separated values, e.g. "/var/log/a.log,/var/log/b/*.log"
+		var list []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+		field.Set(reflect.ValueOf(list))
+	case reflect.Map:
+		// maps are written as comma separated pairs, e.g. "X-Source=notify,X-Env=prod"
+		m := map[string]string{}
+		for _, pair := range strings.Split(value, ",") {
+			if pair == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok {
+				return fmt.Errorf("invalid pair %q, expected KEY=VALUE", pair)
+			}
+			m[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+		field.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// validate checks every field, reporting all the invalid ones at once
+func (c *config) validate() error {
+	var errs []string
+	check := func(ok bool, field, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Sprintf("%s: %s", field, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	check(validOption(c.Input.Format, inputFormats), "input.format", "invalid value %q, valid values: %s", c.Input.Format, strings.Join(inputFormats, ", "))
+	check(validOption(c.Input.Framing, framings), "input.framing", "invalid value %q, valid values: %s", c.Input.Framing, strings.Join(framings, ", "))
+	if c.Input.Framing == "delimiter" {
+		_, err := unquoteDelimiter(c.Input.Delimiter)
+		check(err == nil, "input.delimiter", "%v", err)
+	}
+	if c.Input.Framing == "multiline" {
+		_, err := regexp.Compile(c.Input.MultilinePattern)
+		check(err == nil, "input.multiline_pattern", "%v", err)
+	}
+	check(c.Input.MaxMessageSize > 0, "input.max_message_size", "must be greater than 0, got %d", c.Input.MaxMessageSize)
+	check(validOption(c.Input.Oversize, oversizes), "input.oversize", "invalid value %q, valid values: %s", c.Input.Oversize, strings.Join(oversizes, ", "))
+	if len(c.Input.Tail) > 0 {
+		check(c.Input.Framing == "lines" || c.Input.Framing == "multiline", "input.framing", "only lines and multiline are supported with input.tail, got %q", c.Input.Framing)
+		for _, pattern := range c.Input.Tail {
+			_, err := filepath.Match(pattern, "")
+			check(err == nil, "input.tail", "invalid pattern %q", pattern)
+		}
+		check(c.Input.TailPoll.Duration > 0, "input.tail_poll", "must be greater than 0, got %v", c.Input.TailPoll)
+	}
+
+	check(validOption(c.Ingest.UnixAck, unixAcks), "ingest.unix_ack", "invalid value %q, valid values: %s", c.Ingest.UnixAck, strings.Join(unixAcks, ", "))
+
+	_, err := newPipeline(c.Pipeline)
+	check(err == nil, "pipeline", "%v", err)
+	_, err = c.Redact.redactor()
+	check(err == nil, "redact", "%v", err)
+
+	check(c.Digest.Window.Duration >= 0, "digest.window", "must not be negative, got %v", c.Digest.Window)
+	check(validOption(c.Digest.Key, digestKeys), "digest.key", "invalid value %q, valid values: %s", c.Digest.Key, strings.Join(digestKeys, ", "))
+	check(validOption(c.Digest.Format, digestFormats), "digest.format", "invalid value %q, valid values: %s", c.Digest.Format, strings.Join(digestFormats, ", "))
+	check(c.Digest.MaxGroups > 0, "digest.max_groups", "must be greater than 0, got %d", c.Digest.MaxGroups)
+
+	if c.Endpoint.URL == "" {
+		check(false, "endpoint.url", "missing URL")
+	} else {
+		err := nl.ValidateURL(c.Endpoint.URL)
+		check(err == nil, "endpoint.url", "%v", err)
+	}
+	// the method is case insensitive, as in notilib
+	c.Endpoint.Method = strings.ToUpper(c.Endpoint.Method)
+	check(validMethod(c.Endpoint.Method), "endpoint.method", "invalid value %q, valid values: POST, PUT, PATCH, DELETE, GET", c.Endpoint.Method)
+	check(c.Endpoint.Timeout.Duration >= 0, "endpoint.timeout", "must not be negative, got %v", c.Endpoint.Timeout)
+
+	switch c.Auth.Type {
+	case "none":
+	case "bearer":
+		check(c.Auth.Token != "", "auth.token", "required by the bearer authentication")
+	case "basic":
+		check(c.Auth.Username != "", "auth.username", "required by the basic authentication")
+	default:
+		check(false, "auth.type", "invalid value %q, valid values: none, bearer, basic", c.Auth.Type)
+	}
+
+	check(c.Retry.MaxRetrials >= 0, "retry.max_retrials", "must not be negative, got %d", c.Retry.MaxRetrials)
+	check(c.Retry.DeadLetterCapacity >= 0, "retry.dead_letter_capacity", "must not be negative, got %d", c.Retry.DeadLetterCapacity)
+
+	check(c.Rate.Interval.Duration > 0, "rate.interval", "must be greater than 0, got %v", c.Rate.Interval)
+	check(c.Rate.MessagesPerInterval > 0, "rate.messages_per_interval", "must be greater than 0, got %d", c.Rate.MessagesPerInterval)
+	check(c.Rate.MessagesPerSecond > 0 && c.Rate.MessagesPerSecond <= nl.MaxNumMessagesPerSecond, "rate.messages_per_second", "must be between 1 and %d, got %d", nl.MaxNumMessagesPerSecond, c.Rate.MessagesPerSecond)
+	check(c.Rate.Burst > 0, "rate.burst", "must be greater than 0, got %d", c.Rate.Burst)
+
+	check(validOption(c.Batch.Mode, batchModes), "batch.mode", "invalid value %q, valid values: %s", c.Batch.Mode, strings.Join(batchModes, ", "))
+	check(c.Batch.Size > 0, "batch.size", "must be greater than 0, got %d", c.Batch.Size)
+	check(c.Batch.Linger.Duration >= 0, "batch.linger", "must not be negative, got %v", c.Batch.Linger)
+
+	check(c.Queue.StdinCapacity >= 0, "queue.stdin_capacity", "must not be negative, got %d", c.Queue.StdinCapacity)
+	check(c.Queue.MessageCapacity >= 0, "queue.message_capacity", "must not be negative, got %d", c.Queue.MessageCapacity)
+	check(c.Queue.ErrorCapacity >= 0, "queue.error_capacity", "must not be negative, got %d", c.Queue.ErrorCapacity)
+	_, err = parseOverflow(c.Queue.Overflow, nl.OverflowBlock, nl.OverflowReject)
+	check(err == nil, "queue.overflow", "%v", err)
+	_, err = parseOverflow(c.Queue.PausedOverflow, nl.OverflowBlock, nl.OverflowReject, nl.OverflowSpill)
+	check(err == nil, "queue.paused_overflow", "%v", err)
+
+	_, err = log.ParseLevel(c.Log.Level)
+	check(err == nil, "log.level", "invalid value %q, valid values: trace, debug, info, warn, error, panic, fatal", c.Log.Level)
+
+	check(c.Admin.ReadyQueueThreshold > 0 && c.Admin.ReadyQueueThreshold <= 1, "admin.ready_queue_threshold", "must be in (0, 1], got %v", c.Admin.ReadyQueueThreshold)
+	check(c.Admin.Token == "" || c.Admin.Addr != "", "admin.token", "requires admin.addr")
+
+	check(c.Timeout.Duration > 0, "timeout", "must be greater than 0, got %v", c.Timeout)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func validMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodGet:
+		return true
+	}
+	return false
+}
+
+// parseOverflow returns the overflow policy named value, if it is one of the valid ones
+func parseOverflow(value string, valid ...nl.OverflowPolicy) (nl.OverflowPolicy, error) {
+	names := make([]string, len(valid))
+	for i, policy := range valid {
+		if policy.String() == value {
+			return policy, nil
+		}
+		names[i] = policy.String()
+	}
+	return 0, fmt.Errorf("invalid value %q, valid values: %s", value, strings.Join(names, ", "))
+}
+
+// logLevel returns the log level, the configuration must be valid
+func (c *config) logLevel() log.Level {
+	level, _ := log.ParseLevel(c.Log.Level)
+	return level
+}
+
+// header returns the headers sent with every notification, including the authentication
+func (c *config) header() http.Header {
+	header := http.Header{}
+	for key, value := range c.Endpoint.Headers {
+		header.Set(key, value)
+	}
+	switch c.Auth.Type {
+	case "bearer":
+		header.Set("Authorization", "Bearer "+c.Auth.Token)
+	case "basic":
+		credentials := base64.StdEncoding.EncodeToString([]byte(c.Auth.Username + ":" + c.Auth.Password))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+	return header
+}
+
+// notilibConfig returns the configuration of notilib, the configuration must be valid
+func (c *config) notilibConfig() *nl.Config {
+	overflow, _ := parseOverflow(c.Queue.Overflow, nl.OverflowBlock, nl.OverflowReject)
+	pausedOverflow, _ := parseOverflow(c.Queue.PausedOverflow, nl.OverflowBlock, nl.OverflowReject, nl.OverflowSpill)
+
+	config := nl.DefaultConfig()
+	config.BurstLimit = c.Rate.Burst
+	config.NumMessagesPerSecond = c.Rate.MessagesPerSecond
+	config.MsgChanCap = c.Queue.MessageCapacity
+	config.ErrChanCap = c.Queue.ErrorCapacity
+	config.LogLevel = c.logLevel()
+	config.Overflow = overflow
+	config.PausedOverflow = pausedOverflow
+	config.SpillDir = c.Queue.SpillDir
+	config.Method = c.Endpoint.Method
+	config.Header = c.header()
+	config.DeadLetterCap = c.Retry.DeadLetterCapacity
+	config.Redactor, _ = c.Redact.redactor()
+	if c.Ingest.Unix != "" && c.Ingest.UnixAck == "delivered" {
+		// the deliveries and the discards are reported to answer the unix socket clients, buffered as the errors
+		config.SuccessChanCap = c.Queue.ErrorCapacity
+		config.DiscardChanCap = c.Queue.ErrorCapacity
+	}
+	return config
+}
+
+// httpClient returns the client used by the HTTP transport
+func (c *config) httpClient() *http.Client {
+	if c.Endpoint.Timeout.Duration == 0 {
+		return http.DefaultClient
+	}
+	return &http.Client{Timeout: c.Endpoint.Timeout.Duration}
+}
+
+// masked returns a copy of the configuration with the secrets masked
+func (c config) masked() config {
+	if c.Auth.Token != "" {
+		c.Auth.Token = maskedSecret
+	}
+	if c.Auth.Password != "" {
+		c.Auth.Password = maskedSecret
+	}
+	if c.Admin.Token != "" {
+		c.Admin.Token = maskedSecret
+	}
+	if c.Redact.Salt != "" {
+		c.Redact.Salt = maskedSecret
+	}
+	if len(c.Endpoint.Headers) > 0 {
+		headers := make(map[string]string, len(c.Endpoint.Headers))
+		for k, v := range c.Endpoint.Headers {
+			if strings.EqualFold(k, "Authorization") || strings.EqualFold(k, "Proxy-Authorization") {
+				v = maskedSecret
+			}
+			headers[k] = v
+		}
+		c.Endpoint.Headers = headers
+	}
+	return c
+}
+
+// String returns the configuration as YAML, masking the secrets
+func (c config) String() string {
+	out, err := yaml.Marshal(c.masked())
+	if err != nil {
+		return fmt.Sprintf("unable to encode the configuration: %v", err)
+	}
+	return string(out)
+}
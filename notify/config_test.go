@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadFile(t *testing.T) {
+	tt := []struct {
+		name    string
+		file    string
+		content string
+		errMsg  string
+	}{
+		{"yaml", "notify.yaml", "endpoint:\n  url: http://localhost:9090/api\n  headers:\n    X-Source: notify\nrate:\n  interval: 2s\ninput:\n  tail: [/var/log/a.log]\n", ""},
+		{"yml", "notify.yml", "endpoint:\n  url: http://localhost:9090/api\n  headers:\n    X-Source: notify\nrate:\n  interval: 2s\ninput:\n  tail: [/var/log/a.log]\n", ""},
+		{"toml", "notify.toml", "[endpoint]\nurl = \"http://localhost:9090/api\"\nheaders = { X-Source = \"notify\" }\n[rate]\ninterval = \"2s\"\n[input]\ntail = [\"/var/log/a.log\"]\n", ""},
+		{"json", "notify.json", `{"endpoint": {"url": "http://localhost:9090/api", "headers": {"X-Source": "notify"}}, "rate": {"interval": "2s"}, "input": {"tail": ["/var/log/a.log"]}}`, ""},
+		{"empty yaml", "notify.yaml", "", ""},
+		{"unknown yaml key", "notify.yaml", "endpoint:\n  uri: http://localhost\n", "field uri not found in type main.endpointConfig"},
+		{"unknown toml key", "notify.toml", "[endpoint]\nuri = \"http://localhost\"\n", `unknown field "endpoint.uri"`},
+		{"unknown json key", "notify.json", `{"endpoint": {"uri": "http://localhost"}}`, `json: unknown field "uri"`},
+		{"invalid duration", "notify.yaml", "rate:\n  interval: often\n", `invalid duration "often"`},
+		{"json type error", "notify.json", "{\n  \"retry\": {\"max_retrials\": \"two\"}}", "line 2, column 34: json: cannot unmarshal string"},
+		{"unsupported format", "notify.ini", "url=http://localhost", `unsupported configuration format ".ini", valid extensions: .yaml, .yml, .toml, .json`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.file)
+			if err := ioutil.WriteFile(path, []byte(tc.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			c := defaultConfig()
+			err := c.loadFile(path)
+			if tc.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+					t.Fatalf("expected error containing %q; got %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.content == "" {
+				if !reflect.DeepEqual(c, defaultConfig()) {
+					t.Errorf("expected the default configuration; got %+v", c)
+				}
+				return
+			}
+
+			if c.Endpoint.URL != "http://localhost:9090/api" {
+				t.Errorf("expected endpoint.url http://localhost:9090/api; got %q", c.Endpoint.URL)
+			}
+			if c.Endpoint.Headers["X-Source"] != "notify" {
+				t.Errorf("expected the X-Source header; got %v", c.Endpoint.Headers)
+			}
+			if c.Rate.Interval.Duration != 2*time.Second {
+				t.Errorf("expected rate.interval 2s; got %v", c.Rate.Interval)
+			}
+			if !reflect.DeepEqual(c.Input.Tail, []string{"/var/log/a.log"}) {
+				t.Errorf("expected input.tail [/var/log/a.log]; got %v", c.Input.Tail)
+			}
+			// the fields not in the file keep their default values
+			if c.Endpoint.Method != defaultMethod || c.Rate.MessagesPerSecond != defaultMessagesPerSecond {
+				t.Errorf("expected the default method and messages per second; got %q and %d", c.Endpoint.Method, c.Rate.MessagesPerSecond)
+			}
+		})
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	tt := []struct {
+		name    string
+		environ []string
+		check   func(c *config) bool
+		errMsg  string
+	}{
+		{"string", []string{"NOTIFY_ENDPOINT_URL=http://localhost/api"}, func(c *config) bool { return c.Endpoint.URL == "http://localhost/api" }, ""},
+		{"integer", []string{"NOTIFY_RATE_MESSAGES_PER_SECOND=20"}, func(c *config) bool { return c.Rate.MessagesPerSecond == 20 }, ""},
+		{"float", []string{"NOTIFY_ADMIN_READY_QUEUE_THRESHOLD=0.5"}, func(c *config) bool { return c.Admin.ReadyQueueThreshold == 0.5 }, ""},
+		{"duration", []string{"NOTIFY_RATE_INTERVAL=10s"}, func(c *config) bool { return c.Rate.Interval.Duration == 10*time.Second }, ""},
+		{"list", []string{"NOTIFY_INPUT_TAIL=/var/log/a.log, /var/log/b/*.log,"}, func(c *config) bool {
+			return reflect.DeepEqual(c.Input.Tail, []string{"/var/log/a.log", "/var/log/b/*.log"})
+		}, ""},
+		{"map", []string{"NOTIFY_ENDPOINT_HEADERS=X-Source=notify, X-Env=dev"}, func(c *config) bool {
+			return reflect.DeepEqual(c.Endpoint.Headers, map[string]string{"X-Source": "notify", "X-Env": "dev"})
+		}, ""},
+		{"other variables ignored", []string{"HOME=/root", "NOTIFY_CONFIG=notify.yaml", "NOTIFY_AUTH_TOKEN=abc=="}, func(c *config) bool { return c.Auth.Token == "abc==" }, ""},
+		{"unknown variable", []string{"NOTIFY_ENDPOINT_URI=http://localhost"}, nil, "invalid environment: NOTIFY_ENDPOINT_URI: unknown variable"},
+		{"invalid integer", []string{"NOTIFY_RETRY_MAX_RETRIALS=two"}, nil, `invalid environment: NOTIFY_RETRY_MAX_RETRIALS: invalid integer "two"`},
+		{"invalid duration", []string{"NOTIFY_TIMEOUT=5"}, nil, `invalid environment: NOTIFY_TIMEOUT: invalid duration "5"`},
+		{"invalid pair", []string{"NOTIFY_ENDPOINT_HEADERS=X-Source"}, nil, `invalid environment: NOTIFY_ENDPOINT_HEADERS: invalid pair "X-Source", expected KEY=VALUE`},
+		{"unsupported type", []string{"NOTIFY_PIPELINE=filter"}, nil, "invalid environment: NOTIFY_PIPELINE: unsupported type []main.stageConfig, it can only be set in the configuration file"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaultConfig()
+			err := c.applyEnv(tc.environ)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.check(c) {
+				t.Errorf("unexpected configuration: %+v", c)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		name   string
+		modify func(c *config)
+		errMsg string
+	}{
+		{"valid", func(c *config) {}, ""},
+		{"valid URL template", func(c *config) { c.Endpoint.URL = "http://localhost/api/{{.Metadata.id | path}}" }, ""},
+		{"valid TCP URL", func(c *config) { c.Endpoint.URL = "tcp://localhost:9000" }, ""},
+		{"missing URL", func(c *config) { c.Endpoint.URL = "" }, "invalid configuration: endpoint.url: missing URL"},
+		{"malformed URL", func(c *config) { c.Endpoint.URL = "localhost/api" }, "invalid configuration: endpoint.url: invalid URL"},
+		{"malformed template", func(c *config) { c.Endpoint.URL = "http://localhost/{{.Metadata.id" }, "invalid configuration: endpoint.url: invalid URL template: template: url:1: unclosed action"},
+		{"missing TCP address", func(c *config) { c.Endpoint.URL = "tcp://" }, "invalid configuration: endpoint.url: invalid URL: expected tcp://host:port"},
+		{"invalid method", func(c *config) { c.Endpoint.Method = "HEAD" }, `invalid configuration: endpoint.method: invalid value "HEAD", valid values: POST, PUT, PATCH, DELETE, GET`},
+		{"rate too high", func(c *config) { c.Rate.MessagesPerSecond = 2000000000 }, "invalid configuration: rate.messages_per_second: must be between 1 and 1000000000, got 2000000000"},
+		{"bearer without token", func(c *config) { c.Auth.Type = "bearer" }, "invalid configuration: auth.token: required by the bearer authentication"},
+		{"tail with the length framing", func(c *config) {
+			c.Input.Tail = []string{"/var/log/*.log"}
+			c.Input.Framing = "length"
+		}, `invalid configuration: input.framing: only lines and multiline are supported with input.tail, got "length"`},
+		{"several errors", func(c *config) {
+			c.Rate.Interval = duration{}
+			c.Queue.Overflow = "drop"
+		}, `invalid configuration: rate.interval: must be greater than 0, got 0s; queue.overflow: invalid value "drop", valid values: block, reject`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaultConfig()
+			c.Endpoint.URL = "http://localhost:9090/api/notifications"
+			tc.modify(c)
+
+			err := c.validate()
+			if tc.errMsg == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || err.Error() != tc.errMsg) {
+				t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestMethodCase(t *testing.T) {
+	tt := []struct {
+		name    string
+		args    []string
+		environ string
+	}{
+		{"flag", []string{"--method=put"}, ""},
+		{"environment", nil, "put"},
+		{"mixed case", []string{"--method=Put"}, ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.environ != "" {
+				t.Setenv("NOTIFY_ENDPOINT_METHOD", tc.environ)
+			}
+			c, err := parseFlags(append([]string{"--url=http://localhost:9090/api/notifications"}, tc.args...))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Endpoint.Method != http.MethodPut {
+				t.Errorf("expected method %q; got %q", http.MethodPut, c.Endpoint.Method)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDigestKey = "fingerprint"
+const defaultDigestFormat = "text"
+const defaultDigestMaxGroups = 1000
+
+// digestKeys group the messages of a window by their exact text or by their fingerprint, see fingerprint
+var digestKeys = []string{"text", "fingerprint"}
+
+// digestFormats are the bodies of the summaries, see digestSummary
+var digestFormats = []string{"text", "json"}
+
+// inputDigest groups the messages into summaries, nil without digest
+var inputDigest *digest
+
+// digestConfig enables the digest mode: the messages are grouped during a window and every group is sent as a
+// summary instead of its messages
+type digestConfig struct {
+	Window    duration `yaml:"window" toml:"window" json:"window"`             // 0 disables the digest
+	Key       string   `yaml:"key" toml:"key" json:"key"`                      // text or fingerprint
+	Format    string   `yaml:"format" toml:"format" json:"format"`             // text or json
+	MaxGroups int      `yaml:"max_groups" toml:"max_groups" json:"max_groups"` // the window is flushed early once reached
+}
+
+// fingerprint patterns, replaced in order
+var (
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexPattern    = regexp.MustCompile(`(?i)\b(?:0x[0-9a-f]+|[0-9a-f]{8,})\b`)
+	numberPattern = regexp.MustCompile(`\d+`)
+)
+
+// fingerprint normalizes the text replacing the UUIDs, the hexadecimal identifiers and the numbers, so the
+// messages differing only on them share a group
+func fingerprint(text string) string {
+	text = uuidPattern.ReplaceAllString(text, "<uuid>")
+	text = hexPattern.ReplaceAllString(text, "<hex>")
+	return numberPattern.ReplaceAllString(text, "<n>")
+}
+
+// groupKey identifies a group: the messages share the key and the target, so the summary is sent where they would be
+type groupKey struct {
+	key    string
+	method string
+	path   string
+}
+
+// digestGroup are the messages of a window sharing a key and a target
+type digestGroup struct {
+	key       string
+	sample    nl.Notification // first message of the group
+	count     int
+	firstSeen time.Time
+	lastSeen  time.Time
+	tracked   []nl.Notification // messages of the unix socket clients or the followed files, answered or committed once the summary is queued
+}
+
+// digestSummary is the body of a summary with the json format
+type digestSummary struct {
+	Key       string    `json:"key"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	Sample    string    `json:"sample"`
+}
+
+type digest struct {
+	config    digestConfig
+	digested  uint64 // messages grouped since the start
+	summaries uint64 // summaries queued since the start
+
+	mu     sync.Mutex
+	groups map[groupKey]*digestGroup
+	order  []*digestGroup // the summaries keep the order of arrival of the groups
+	count  int            // messages waiting in the groups
+}
+
+func newDigest(c digestConfig) *digest {
+	return &digest{config: c, groups: map[groupKey]*digestGroup{}}
+}
+
+// run flushes the groups at the end of every window
+func (d *digest) run() {
+	ticker := time.NewTicker(d.config.Window.Duration)
+	for range ticker.C {
+		d.flush()
+	}
+}
+
+// add groups the message received at the time, the window is flushed first if it is full
+func (d *digest) add(n nl.Notification, at time.Time) {
+	key := string(n.Body)
+	if d.config.Key == "fingerprint" {
+		key = fingerprint(key)
+	}
+
+	gk := groupKey{key: key, method: n.Method, path: n.Path}
+
+	d.mu.Lock()
+	var full []*digestGroup
+	g, ok := d.groups[gk]
+	if !ok && len(d.groups) >= d.config.MaxGroups {
+		full = d.take()
+	}
+	if !ok {
+		g = &digestGroup{key: key, sample: n, firstSeen: at}
+		d.groups[gk] = g
+		d.order = append(d.order, g)
+	}
+	g.count++
+	g.lastSeen = at
+	if tracked(n) {
+		g.tracked = append(g.tracked, nl.Notification{Metadata: n.Metadata})
+	}
+	d.count++
+	d.mu.Unlock()
+	atomic.AddUint64(&d.digested, 1)
+
+	if full != nil {
+		log.Warnf("digest window flushed early, %d groups reached", len(full))
+		d.send(full)
+	}
+}
+
+// take empties the window returning its groups, the lock must be held
+func (d *digest) take() []*digestGroup {
+	groups := d.order
+	d.groups = map[groupKey]*digestGroup{}
+	d.order = nil
+	d.count = 0
+	return groups
+}
+
+// flush sends a summary of every group of the window
+func (d *digest) flush() {
+	d.mu.Lock()
+	groups := d.take()
+	d.mu.Unlock()
+	if len(groups) > 0 {
+		d.send(groups)
+	}
+}
+
+// pending returns the number of groups and messages of the window
+func (d *digest) pending() (int, int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.order), d.count
+}
+
+func (d *digest) send(groups []*digestGroup) {
+	messages := make([]nl.Notification, len(groups))
+	for i, g := range groups {
+		messages[i] = d.summary(g)
+	}
+
+	guid, err := notilib.NotifyMessages(context.Background(), messages)
+	queued := len(messages)
+	if err != nil {
+		log.Errorf("notifier client has reported a failure: %v", err)
+		queued = queuedCount(err)
+		inputOutcomes.addFailed(len(messages) - queued)
+		for _, g := range groups[queued:] {
+			for _, n := range g.tracked {
+				inputAcks.failed(n, "", 0, err)
+			}
+			inputCommits.forget(g.tracked...)
+		}
+	}
+	if queued == 0 {
+		return
+	}
+	atomic.AddUint64(&d.summaries, uint64(queued))
+	inputOutcomes.addQueued(queued)
+	log.Infof("summaries received: GUID=%s", guid)
+	for i, g := range groups[:queued] {
+		inputAcks.digested(g.tracked, guid, i)
+		inputCommits.commit(g.tracked...)
+	}
+}
+
+// tracked reports whether the message is awaited by a unix socket client or has to be committed
+func tracked(n nl.Notification) bool {
+	_, ack := n.Metadata[ackMetadataKey]
+	_, commit := n.Metadata[commitMetadataKey]
+	return ack || commit
+}
+
+// summary returns the notification of the group, it keeps the target and the metadata of the sample adding the
+// digest_* fields
+func (d *digest) summary(g *digestGroup) nl.Notification {
+	n := nl.Notification{
+		Method:   g.sample.Method,
+		Path:     g.sample.Path,
+		Metadata: make(map[string]string, len(g.sample.Metadata)+4),
+	}
+	for key, value := range g.sample.Metadata {
+		if key != ackMetadataKey && key != commitMetadataKey {
+			n.Metadata[key] = value
+		}
+	}
+	n.Metadata["digest_key"] = g.key
+	n.Metadata["digest_count"] = fmt.Sprint(g.count)
+	n.Metadata["digest_first_seen"] = g.firstSeen.Format(time.RFC3339)
+	n.Metadata["digest_last_seen"] = g.lastSeen.Format(time.RFC3339)
+
+	if d.config.Format == "json" {
+		// the placeholders of the fingerprints are kept readable
+		var body bytes.Buffer
+		enc := json.NewEncoder(&body)
+		enc.SetEscapeHTML(false)
+		enc.Encode(digestSummary{
+			Key:       g.key,
+			Count:     g.count,
+			FirstSeen: g.firstSeen,
+			LastSeen:  g.lastSeen,
+			Sample:    string(g.sample.Body),
+		})
+		n.Body = bytes.TrimSuffix(body.Bytes(), []byte("\n"))
+		n.ContentType = "application/json"
+		return n
+	}
+	n.Body = []byte(fmt.Sprintf("%s (count: %d, first seen: %s, last seen: %s)", g.sample.Body, g.count,
+		g.firstSeen.Format(time.RFC3339), g.lastSeen.Format(time.RFC3339)))
+	n.ContentType = g.sample.ContentType
+	return n
+}
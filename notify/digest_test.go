@@ -0,0 +1,125 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+func TestFingerprint(t *testing.T) {
+	tt := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"no variable parts", "disk full", "disk full"},
+		{"numbers", "retry 3 of 5 after 250ms on db12", "retry <n> of <n> after <n>ms on db<n>"},
+		{"ip address", "connection refused by 10.0.0.12:5432", "connection refused by <n>.<n>.<n>.<n>:<n>"},
+		{"uuid", "order 0e527ed5-45a3-4c48-8b96-6fdc709da90d failed", "order <uuid> failed"},
+		{"hexadecimal", "commit a3f9c2e1b7 at 0x7ffe, deadline exceeded", "commit <hex> at <hex>, deadline exceeded"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fingerprint(tc.text); got != tc.expected {
+				t.Errorf("expected %q; got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDigest(t *testing.T) {
+	start := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	messages := []string{
+		"timeout on db1 after 30s",
+		"disk full",
+		"timeout on db2 after 31s",
+		"timeout on db1 after 30s",
+	}
+
+	tt := []struct {
+		name      string
+		config    digestConfig
+		summaries []string
+	}{
+		{"text key", digestConfig{Key: "text", Format: "text", MaxGroups: 10}, []string{
+			"timeout on db1 after 30s (count: 2, first seen: 2026-10-18T10:00:00Z, last seen: 2026-10-18T10:00:03Z)",
+			"disk full (count: 1, first seen: 2026-10-18T10:00:01Z, last seen: 2026-10-18T10:00:01Z)",
+			"timeout on db2 after 31s (count: 1, first seen: 2026-10-18T10:00:02Z, last seen: 2026-10-18T10:00:02Z)",
+		}},
+		{"fingerprint key", digestConfig{Key: "fingerprint", Format: "text", MaxGroups: 10}, []string{
+			"timeout on db1 after 30s (count: 3, first seen: 2026-10-18T10:00:00Z, last seen: 2026-10-18T10:00:03Z)",
+			"disk full (count: 1, first seen: 2026-10-18T10:00:01Z, last seen: 2026-10-18T10:00:01Z)",
+		}},
+		{"json format", digestConfig{Key: "fingerprint", Format: "json", MaxGroups: 10}, []string{
+			`{"key":"timeout on db<n> after <n>s","count":3,"first_seen":"2026-10-18T10:00:00Z","last_seen":"2026-10-18T10:00:03Z","sample":"timeout on db1 after 30s"}`,
+			`{"key":"disk full","count":1,"first_seen":"2026-10-18T10:00:01Z","last_seen":"2026-10-18T10:00:01Z","sample":"disk full"}`,
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newDigest(tc.config)
+			for i, msg := range messages {
+				d.add(nl.Notification{Body: []byte(msg)}, start.Add(time.Duration(i)*time.Second))
+			}
+			if groups, pending := d.pending(); groups != len(tc.summaries) || pending != len(messages) {
+				t.Errorf("expected %d groups and %d messages; got %d and %d", len(tc.summaries), len(messages), groups, pending)
+			}
+
+			var summaries []string
+			for _, g := range d.take() {
+				summaries = append(summaries, string(d.summary(g).Body))
+			}
+			if !reflect.DeepEqual(summaries, tc.summaries) {
+				t.Errorf("expected summaries:\n%q\ngot:\n%q", tc.summaries, summaries)
+			}
+			if groups, pending := d.pending(); groups != 0 || pending != 0 {
+				t.Errorf("expected an empty window; got %d groups and %d messages", groups, pending)
+			}
+		})
+	}
+}
+
+func TestDigestTargets(t *testing.T) {
+	d := newDigest(digestConfig{Key: "text", Format: "text", MaxGroups: 10})
+	at := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	d.add(nl.Notification{Body: []byte("disk full"), Path: "alerts"}, at)
+	d.add(nl.Notification{Body: []byte("disk full")}, at)
+	d.add(nl.Notification{Body: []byte("disk full"), Path: "alerts", Method: "PUT"}, at)
+	d.add(nl.Notification{Body: []byte("disk full"), Path: "alerts"}, at)
+
+	type target struct {
+		method, path string
+		count        string
+	}
+	expected := []target{{"", "alerts", "2"}, {"", "", "1"}, {"PUT", "alerts", "1"}}
+	var targets []target
+	for _, g := range d.take() {
+		n := d.summary(g)
+		targets = append(targets, target{n.Method, n.Path, n.Metadata["digest_count"]})
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("expected summaries %+v; got %+v", expected, targets)
+	}
+}
+
+func TestDigestSummaryMetadata(t *testing.T) {
+	d := newDigest(digestConfig{Key: "text", Format: "text", MaxGroups: 10})
+	at := time.Date(2026, 10, 18, 10, 0, 0, 0, time.UTC)
+	d.add(nl.Notification{Body: []byte("disk full"), Metadata: map[string]string{"host": "db1", ackMetadataKey: "1"}}, at)
+
+	n := d.summary(d.take()[0])
+	expected := map[string]string{
+		"host":              "db1",
+		"digest_key":        "disk full",
+		"digest_count":      "1",
+		"digest_first_seen": "2026-10-18T10:00:00Z",
+		"digest_last_seen":  "2026-10-18T10:00:00Z",
+	}
+	if !reflect.DeepEqual(n.Metadata, expected) {
+		t.Errorf("expected metadata %v; got %v", expected, n.Metadata)
+	}
+}
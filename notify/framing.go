@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultFraming = "lines"
+const defaultMultilinePattern = `^\s`
+const defaultMaxMessageSize = 1 << 20
+const defaultOversize = "reject"
+
+// multilineTimeout is the time waited for the continuation lines before sending a multiline message
+const multilineTimeout = time.Second
+
+// framings are the ways of splitting the input into messages:
+// lines ends every message with a new line, delimiter with a custom delimiter, nul with a NUL byte,
+// length prefixes every message with its size as a 4 bytes big-endian integer, and multiline reads lines
+// joining those matching the multiline pattern to the previous one (e.g. the lines of a stack trace)
+var framings = []string{"lines", "delimiter", "nul", "length", "multiline"}
+
+// oversizes are the policies for the messages bigger than the max size: truncate them or reject them
+var oversizes = []string{"truncate", "reject"}
+
+// frame is a message read from the input
+type frame struct {
+	data      []byte
+	oversized bool   // data has been truncated to the max size
+	commit    func() // called once the message has been queued into notilib, nil if the input does not track it
+}
+
+// framer reads the messages from the input
+type framer interface {
+	next() (frame, error)
+}
+
+// newFramer returns the framer of the input configuration, the configuration must be valid
+func newFramer(r io.Reader, c inputConfig) framer {
+	br := bufio.NewReader(r)
+	switch c.Framing {
+	case "delimiter":
+		delim, _ := unquoteDelimiter(c.Delimiter)
+		return &delimitedFramer{r: br, delim: []byte(delim), max: c.MaxMessageSize}
+	case "nul":
+		return &delimitedFramer{r: br, delim: []byte{0}, max: c.MaxMessageSize}
+	case "length":
+		return &lengthFramer{r: br, max: c.MaxMessageSize}
+	}
+	return &delimitedFramer{r: br, delim: []byte("\n"), trimCR: true, max: c.MaxMessageSize}
+}
+
+// unquoteDelimiter interprets the escape sequences of the delimiter, e.g. "\r\n" or "\x1e"
+func unquoteDelimiter(delim string) (string, error) {
+	if delim == "" {
+		return "", fmt.Errorf("missing delimiter")
+	}
+	unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(delim, `"`, `\"`) + `"`)
+	if err != nil {
+		return "", fmt.Errorf("invalid delimiter %q", delim)
+	}
+	return unquoted, nil
+}
+
+// delimitedFramer splits the input by a delimiter, the messages bigger than max are truncated
+type delimitedFramer struct {
+	r      *bufio.Reader
+	delim  []byte
+	trimCR bool // remove the \r of the \r\n line endings
+	max    int
+}
+
+func (f *delimitedFramer) next() (frame, error) {
+	last := f.delim[len(f.delim)-1]
+
+	// data keeps up to max bytes plus the delimiter, window the last bytes read for finding the delimiter
+	var data, window []byte
+	total := 0
+	for {
+		chunk, err := f.r.ReadSlice(last)
+		total += len(chunk)
+		if room := f.max + len(f.delim) - len(data); room > 0 {
+			data = append(data, chunk[:min(room, len(chunk))]...)
+		}
+		window = append(window, chunk...)
+		if len(window) > len(f.delim) {
+			window = window[len(window)-len(f.delim):]
+		}
+
+		switch {
+		case err == nil && bytes.Equal(window, f.delim):
+			return f.frame(data, total-len(f.delim)), nil
+		case err == nil || err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && total > 0:
+			// the last message does not end with the delimiter
+			return f.frame(data, total), nil
+		default:
+			return frame{}, err
+		}
+	}
+}
+
+// frame returns the first size bytes of data, truncated to the max size. data keeps max+1 bytes at least, so the
+// \r of a line of max bytes is found.
+func (f *delimitedFramer) frame(data []byte, size int) frame {
+	if f.trimCR && size <= f.max+1 && size > 0 && data[size-1] == '\r' {
+		size--
+	}
+	return frame{data: data[:min(size, f.max)], oversized: size > f.max}
+}
+
+// lengthFramer reads messages prefixed by their size as a 4 bytes big-endian integer
+type lengthFramer struct {
+	r   *bufio.Reader
+	max int
+}
+
+func (f *lengthFramer) next() (frame, error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(f.r, prefix[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return frame{}, fmt.Errorf("truncated length prefix")
+		}
+		return frame{}, err
+	}
+	size := int(binary.BigEndian.Uint32(prefix[:]))
+
+	data := make([]byte, min(size, f.max))
+	if _, err := io.ReadFull(f.r, data); err != nil {
+		return frame{}, fmt.Errorf("truncated message of %d bytes: %v", size, err)
+	}
+	if size > f.max {
+		if _, err := io.CopyN(io.Discard, f.r, int64(size-f.max)); err != nil {
+			return frame{}, fmt.Errorf("truncated message of %d bytes: %v", size, err)
+		}
+	}
+	return frame{data: data, oversized: size > f.max}, nil
+}
+
+// multiline joins the lines matching the continuation pattern to the previous message
+type multiline struct {
+	continuation *regexp.Regexp
+	max          int
+	pending      *frame // message waiting for its continuation lines
+}
+
+// add returns the pending message when line starts a new one
+func (m *multiline) add(line frame) (frame, bool) {
+	if m.pending != nil && m.continuation.Match(line.data) {
+		joined := append(append(m.pending.data, '\n'), line.data...)
+		m.pending.oversized = m.pending.oversized || line.oversized || len(joined) > m.max
+		m.pending.data = joined[:min(len(joined), m.max)]
+		m.pending.commit = line.commit
+		return frame{}, false
+	}
+
+	previous := m.pending
+	m.pending = &line
+	if previous == nil {
+		return frame{}, false
+	}
+	return *previous, true
+}
+
+// flush returns the pending message, if any
+func (m *multiline) flush() (frame, bool) {
+	if m.pending == nil {
+		return frame{}, false
+	}
+	previous := m.pending
+	m.pending = nil
+	return *previous, true
+}
+
+// readFrames reads the messages of the framer in background. Once the input ends, the error (nil at EOF)
+// is sent to the error channel and the frames channel is closed.
+func readFrames(f framer) (<-chan frame, <-chan error) {
+	frames := make(chan frame)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(frames)
+		for {
+			fr, err := f.next()
+			if err == io.EOF {
+				errc <- nil
+				return
+			}
+			if err != nil {
+				errc <- err
+				return
+			}
+			frames <- fr
+		}
+	}()
+	return frames, errc
+}
+
+func validOption(value string, valid []string) bool {
+	for _, v := range valid {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
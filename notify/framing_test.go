@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+// chunkReader returns its chunks in separate reads
+type chunkReader struct {
+	chunks []string
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.chunks[0])
+	if r.chunks[0] = r.chunks[0][n:]; r.chunks[0] == "" {
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+// lengthPrefixed frames the messages with their size as a 4 bytes big-endian integer
+func lengthPrefixed(messages ...string) string {
+	var buf bytes.Buffer
+	for _, msg := range messages {
+		binary.Write(&buf, binary.BigEndian, uint32(len(msg)))
+		buf.WriteString(msg)
+	}
+	return buf.String()
+}
+
+// readAllFrames returns the data of the frames, marking the oversized ones with a trailing "+", and the error
+// ending the input, nil at EOF
+func readAllFrames(f framer) ([]string, error) {
+	frames := []string{}
+	for {
+		fr, err := f.next()
+		if err == io.EOF {
+			return frames, nil
+		}
+		if err != nil {
+			return frames, err
+		}
+		data := string(fr.data)
+		if fr.oversized {
+			data += "+"
+		}
+		frames = append(frames, data)
+	}
+}
+
+func TestFramers(t *testing.T) {
+	long := strings.Repeat("x", 5000)
+
+	tt := []struct {
+		name      string
+		framing   string
+		delimiter string
+		max       int
+		chunks    []string
+		expected  []string
+		errMsg    string
+	}{
+		{"lines", "lines", "", 100, []string{"a\nb\r\n\nc"}, []string{"a", "b", "", "c"}, ""},
+		{"lines split across reads", "lines", "", 100, []string{"ab", "c\nd", "e\n"}, []string{"abc", "de"}, ""},
+		{"lines oversized", "lines", "", 3, []string{"abcdef\nab\nabc\r\n"}, []string{"abc+", "ab", "abc"}, ""},
+		{"lines longer than the buffer", "lines", "", 6000, []string{long + "\nb\n"}, []string{long, "b"}, ""},
+		{"lines longer than the buffer oversized", "lines", "", 10, []string{long + "\nb\n"}, []string{"xxxxxxxxxx+", "b"}, ""},
+		{"delimiter", "delimiter", `\r\n`, 100, []string{"a\r\nb\nc\r\n"}, []string{"a", "b\nc"}, ""},
+		{"delimiter split across reads", "delimiter", "<EOM>", 100, []string{"first<E", "O", "M>sec<EO", "M>third"}, []string{"first", "sec", "third"}, ""},
+		{"delimiter with its last byte inside", "delimiter", "-->", 100, []string{"a>b-->", "c>-->"}, []string{"a>b", "c>"}, ""},
+		{"delimiter oversized", "delimiter", "<EOM>", 4, []string{"abcdefgh<EO", "M>abcd<EOM>"}, []string{"abcd+", "abcd"}, ""},
+		{"nul", "nul", "", 100, []string{"a\x00b\n\x00", "c"}, []string{"a", "b\n", "c"}, ""},
+		{"nul oversized", "nul", "", 2, []string{"abc\x00ab\x00"}, []string{"ab+", "ab"}, ""},
+		{"length", "length", "", 100, []string{lengthPrefixed("a", "bc\n", "")}, []string{"a", "bc\n", ""}, ""},
+		{"length split across reads", "length", "", 100, []string{lengthPrefixed("abc")[:2], lengthPrefixed("abc")[2:5], lengthPrefixed("abc")[5:]}, []string{"abc"}, ""},
+		{"length oversized", "length", "", 3, []string{lengthPrefixed("abcdef", "abc")}, []string{"abc+", "abc"}, ""},
+		{"truncated length prefix", "length", "", 100, []string{lengthPrefixed("a") + "\x00\x00"}, []string{"a"}, "truncated length prefix"},
+		{"truncated message", "length", "", 100, []string{lengthPrefixed("abcdef")[:6]}, []string{}, "truncated message of 6 bytes: unexpected EOF"},
+		{"truncated oversized message", "length", "", 3, []string{lengthPrefixed("abcdef")[:8]}, []string{}, "truncated message of 6 bytes: EOF"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := inputConfig{Framing: tc.framing, Delimiter: tc.delimiter, MaxMessageSize: tc.max}
+			frames, err := readAllFrames(newFramer(&chunkReader{chunks: append([]string{}, tc.chunks...)}, c))
+			if tc.errMsg == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || err.Error() != tc.errMsg) {
+				t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+			}
+			if !reflect.DeepEqual(frames, tc.expected) {
+				t.Errorf("expected frames %q; got %q", tc.expected, frames)
+			}
+		})
+	}
+}
+
+func TestUnquoteDelimiter(t *testing.T) {
+	tt := []struct {
+		name      string
+		delimiter string
+		expected  string
+		errMsg    string
+	}{
+		{"plain", "<EOM>", "<EOM>", ""},
+		{"escaped", `\r\n`, "\r\n", ""},
+		{"hexadecimal", `\x1e`, "\x1e", ""},
+		{"quotes", `"`, `"`, ""},
+		{"missing", "", "", "missing delimiter"},
+		{"invalid escape", `\q`, "", `invalid delimiter "\\q"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			delim, err := unquoteDelimiter(tc.delimiter)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if delim != tc.expected {
+				t.Errorf("expected delimiter %q; got %q", tc.expected, delim)
+			}
+		})
+	}
+}
+
+func TestMultiline(t *testing.T) {
+	tt := []struct {
+		name     string
+		max      int
+		lines    []string
+		expected []string
+	}{
+		{"single lines", 100, []string{"a", "b"}, []string{"a", "b"}},
+		{"continuation lines", 100, []string{"panic: boom", "  at main.go:10", "\tat lib.go:3", "next"}, []string{"panic: boom\n  at main.go:10\n\tat lib.go:3", "next"}},
+		{"first line is a continuation", 100, []string{" a", "b"}, []string{" a", "b"}},
+		{"oversized", 8, []string{"error", " at x", "next"}, []string{"error\n a+", "next"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &multiline{continuation: regexp.MustCompile(defaultMultilinePattern), max: tc.max}
+			messages := []string{}
+			collect := func(f frame, ok bool) {
+				if !ok {
+					return
+				}
+				data := string(f.data)
+				if f.oversized {
+					data += "+"
+				}
+				messages = append(messages, data)
+			}
+			for _, line := range tc.lines {
+				collect(m.add(frame{data: []byte(line)}))
+			}
+			collect(m.flush())
+
+			if !reflect.DeepEqual(messages, tc.expected) {
+				t.Errorf("expected messages %q; got %q", tc.expected, messages)
+			}
+		})
+	}
+}
+
+// recordingSink keeps the messages pushed and rejected by readInput
+type recordingSink struct {
+	pushed   []string
+	rejected []string
+}
+
+func (s *recordingSink) push(num int, n nl.Notification) {
+	s.pushed = append(s.pushed, string(n.Body))
+}
+
+func (s *recordingSink) reject(num int, data []byte, err error) {
+	s.rejected = append(s.rejected, string(data)+": "+err.Error())
+}
+
+func TestOversize(t *testing.T) {
+	tt := []struct {
+		name     string
+		framing  string
+		oversize string
+		input    string
+		pushed   []string
+		rejected []string
+	}{
+		{"lines truncated", "lines", "truncate", "short\nway too long\n", []string{"short", "way t"}, nil},
+		{"lines rejected", "lines", "reject", "short\nway too long\n", []string{"short"}, []string{"way t: message bigger than 5 bytes"}},
+		{"length truncated", "length", "truncate", lengthPrefixed("short", "way too long"), []string{"short", "way t"}, nil},
+		{"length rejected", "length", "reject", lengthPrefixed("short", "way too long"), []string{"short"}, []string{"way t: message bigger than 5 bytes"}},
+		{"multiline truncated", "multiline", "truncate", "err\n at\nnext\n", []string{"err\n ", "next"}, nil},
+		{"multiline rejected", "multiline", "reject", "err\n at\nnext\n", []string{"next"}, []string{"err\n : message bigger than 5 bytes"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := inputConfig{
+				Format:           "text",
+				Framing:          tc.framing,
+				MultilinePattern: defaultMultilinePattern,
+				MaxMessageSize:   5,
+				Oversize:         tc.oversize,
+			}
+			sink := &recordingSink{}
+			if err := readInput(newFramer(strings.NewReader(tc.input), c), c, newDecoder(c.Format), sink); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(sink.pushed, tc.pushed) {
+				t.Errorf("expected messages %q; got %q", tc.pushed, sink.pushed)
+			}
+			if !reflect.DeepEqual(sink.rejected, tc.rejected) {
+				t.Errorf("expected rejects %q; got %q", tc.rejected, sink.rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+// ingestPath is the path of the HTTP ingest endpoint
+const ingestPath = "/messages"
+
+// ingestRetryAfter is the time suggested to the HTTP clients when the Stdin Channel is full
+const ingestRetryAfter = 5 * time.Second
+
+// ingestDropped counts the syslog UDP datagrams dropped because the Stdin Channel was full
+var ingestDropped uint64
+
+// ingest holds the network listeners feeding the Stdin Channel
+type ingest struct {
+	input inputConfig
+	ack   string // response awaited by the unix socket clients
+	ch    chan<- nl.Notification
+
+	mu      sync.Mutex // guards closers
+	closers map[io.Closer]bool
+}
+
+// ingestResponse is the response of the HTTP ingest endpoint
+type ingestResponse struct {
+	Accepted int    `json:"accepted"`
+	Error    string `json:"error,omitempty"`
+}
+
+// startIngest binds the configured listeners, the messages received are inserted into ch.
+// The listeners are bound before returning so the address errors are reported on start.
+func startIngest(c ingestConfig, input inputConfig, ch chan<- nl.Notification) (*ingest, error) {
+	in := &ingest{input: input, ack: c.UnixAck, ch: ch, closers: map[io.Closer]bool{}}
+	start := []struct {
+		addr  string
+		serve func(addr string) error
+	}{
+		{c.HTTP, in.serveHTTP},
+		{c.TCP, in.serveTCP},
+		{c.SyslogUDP, in.serveSyslogUDP},
+		{c.SyslogTCP, in.serveSyslogTCP},
+		{c.Unix, in.serveUnix},
+	}
+	for _, s := range start {
+		if s.addr == "" {
+			continue
+		}
+		if err := s.serve(s.addr); err != nil {
+			in.close()
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+// close stops accepting messages, the connections already open are closed too
+func (in *ingest) close() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	for c := range in.closers {
+		c.Close()
+	}
+	in.closers = map[io.Closer]bool{}
+}
+
+// track registers a listener or connection to be closed by close
+func (in *ingest) track(c io.Closer) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.closers[c] = true
+}
+
+func (in *ingest) untrack(c io.Closer) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	delete(in.closers, c)
+}
+
+// serveHTTP accepts messages on POST /messages, see handleIngest
+func (in *ingest) serveHTTP(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen for HTTP messages: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(ingestPath, in.handleIngest)
+	srv := &http.Server{Handler: mux}
+	in.track(srv)
+
+	go func() {
+		log.Infof("accepting HTTP messages on %s%s", l.Addr(), ingestPath)
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Errorf("HTTP ingest stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// handleIngest queues the messages of the request body. A JSON array is a list of messages, where every element
+// is either a string, sent as text, or an object with the fields of the jsonl input. A JSON object is a single
+// message with the fields of the jsonl input. Any other body is sent as it is, with its Content-Type.
+// The response is 202 with the number of messages accepted, or 503 with Retry-After when the Stdin Channel is full.
+func (in *ingest) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(in.input.MaxMessageSize)))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("body bigger than %d bytes", in.input.MaxMessageSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, fmt.Sprintf("unable to read the body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		http.Error(w, "empty body", http.StatusBadRequest)
+		return
+	}
+
+	messages, err := decodeIngestBody(body, r.Header.Get("Content-Type"))
+	if err != nil {
+		inputRejects.reject("http", 1, body, err)
+		http.Error(w, fmt.Sprintf("invalid message: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if isTerminating.Load() {
+		writeIngestResponse(w, http.StatusServiceUnavailable, ingestResponse{Error: "terminating"})
+		return
+	}
+	if room := cap(in.ch) - len(in.ch); room < len(messages) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(ingestRetryAfter.Seconds())))
+		writeIngestResponse(w, http.StatusServiceUnavailable, ingestResponse{Error: "queue full"})
+		return
+	}
+
+	// other inputs may fill the room left, the messages not accepted are reported
+	accepted := 0
+	for _, n := range messages {
+		select {
+		case in.ch <- n:
+			accepted++
+		default:
+			w.Header().Set("Retry-After", strconv.Itoa(int(ingestRetryAfter.Seconds())))
+			writeIngestResponse(w, http.StatusServiceUnavailable, ingestResponse{Accepted: accepted, Error: "queue full"})
+			return
+		}
+	}
+	writeIngestResponse(w, http.StatusAccepted, ingestResponse{Accepted: accepted})
+}
+
+// decodeIngestBody returns the messages of an HTTP ingest body
+func decodeIngestBody(body []byte, contentType string) ([]nl.Notification, error) {
+	trimmed := bytes.TrimSpace(body)
+	if !strings.HasPrefix(contentType, "application/json") {
+		return []nl.Notification{{Body: body, ContentType: contentType}}, nil
+	}
+	if trimmed[0] != '[' {
+		n, err := decodeJSONMessage(trimmed)
+		if err != nil {
+			return nil, err
+		}
+		return []nl.Notification{n}, nil
+	}
+
+	var elements []json.RawMessage
+	if err := json.Unmarshal(trimmed, &elements); err != nil {
+		return nil, err
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("empty array")
+	}
+	messages := make([]nl.Notification, 0, len(elements))
+	for i, element := range elements {
+		var text string
+		if err := json.Unmarshal(element, &text); err == nil {
+			messages = append(messages, nl.Notification{Body: []byte(text)})
+			continue
+		}
+		n, err := decodeJSONMessage(element)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %v", i, err)
+		}
+		messages = append(messages, n)
+	}
+	return messages, nil
+}
+
+func writeIngestResponse(w http.ResponseWriter, status int, res ingestResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Errorf("unable to encode the response: %v", err)
+	}
+}
+
+// serveTCP reads the messages of every connection with the input format and framing. A connection is not read
+// while the Stdin Channel is full, so the senders are slowed down by the TCP flow control.
+func (in *ingest) serveTCP(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen for tcp messages: %v", err)
+	}
+	decode := newDecoder(in.input.Format)
+	in.serveConnections("tcp", l, func(conn net.Conn, source string) error {
+		return readInput(newFramer(conn, in.input), in.input, decode, channelSink{ch: in.ch, name: source})
+	})
+	return nil
+}
+
+// serveSyslogTCP reads RFC 5424 messages framed as RFC 6587, see syslogFramer
+func (in *ingest) serveSyslogTCP(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen for syslog-tcp messages: %v", err)
+	}
+	// the framing of the input does not apply, every syslog frame is a message
+	input := in.input
+	input.Framing = "lines"
+	in.serveConnections("syslog-tcp", l, func(conn net.Conn, source string) error {
+		frames := &syslogFramer{r: bufio.NewReader(conn), max: input.MaxMessageSize}
+		return readInput(frames, input, parseSyslog, channelSink{ch: in.ch, name: source})
+	})
+	return nil
+}
+
+// serveConnections handles every connection accepted by the listener in background, as an input of its own
+func (in *ingest) serveConnections(name string, l net.Listener, handle func(conn net.Conn, source string) error) {
+	in.track(l)
+
+	go func() {
+		log.Infof("accepting %s messages on %s", name, l.Addr())
+		for num := 1; ; num++ {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Errorf("%s listener stopped: %v", name, err)
+				}
+				return
+			}
+			in.track(conn)
+			go func() {
+				defer in.untrack(conn)
+				defer conn.Close()
+				source := fmt.Sprintf("%s %s", name, conn.RemoteAddr())
+				if l.Addr().Network() == "unix" {
+					// the clients of a unix socket have no address
+					source = fmt.Sprintf("%s #%d", name, num)
+				}
+				log.Debugf("%s connected", source)
+				if err := handle(conn, source); err != nil && !errors.Is(err, net.ErrClosed) {
+					log.Warnf("%s: %v", source, err)
+				}
+				log.Debugf("%s disconnected", source)
+			}()
+		}
+	}()
+}
+
+// serveSyslogUDP reads a RFC 5424 message per datagram. The datagrams received while the Stdin Channel is full
+// are dropped, as the sender can not be slowed down.
+func (in *ingest) serveSyslogUDP(addr string) error {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("unable to listen for syslog-udp messages: %v", err)
+	}
+	in.track(conn)
+
+	go func() {
+		log.Infof("accepting syslog-udp messages on %s", conn.LocalAddr())
+		buf := make([]byte, 64*1024)
+		numMsg := 0
+		for {
+			size, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					log.Errorf("syslog-udp listener stopped: %v", err)
+				}
+				return
+			}
+			numMsg++
+			data := bytes.Clone(bytes.TrimRight(buf[:size], "\r\n"))
+			source := fmt.Sprintf("syslog-udp %s", from)
+			if len(data) > in.input.MaxMessageSize && in.input.Oversize == "reject" {
+				inputRejects.reject(source, numMsg, data[:in.input.MaxMessageSize], fmt.Errorf("message bigger than %d bytes", in.input.MaxMessageSize))
+				continue
+			}
+			data = data[:min(len(data), in.input.MaxMessageSize)]
+
+			n, err := parseSyslog(data)
+			if err != nil {
+				inputRejects.reject(source, numMsg, data, err)
+				continue
+			}
+			select {
+			case in.ch <- n:
+			default:
+				atomic.AddUint64(&ingestDropped, 1)
+				log.Warnf("message %d from %s dropped: queue full", numMsg, source)
+			}
+		}
+	}()
+	return nil
+}
+
+// syslogFramer splits a syslog TCP stream as RFC 6587: a message starting with a digit is prefixed by its
+// size and a space (octet counting), otherwise it ends with a new line (non-transparent framing)
+type syslogFramer struct {
+	r     *bufio.Reader
+	max   int
+	lines *delimitedFramer
+}
+
+func (f *syslogFramer) next() (frame, error) {
+	first, err := f.r.Peek(1)
+	if err != nil {
+		return frame{}, err
+	}
+	if first[0] < '0' || first[0] > '9' {
+		if f.lines == nil {
+			f.lines = &delimitedFramer{r: f.r, delim: []byte("\n"), trimCR: true, max: f.max}
+		}
+		return f.lines.next()
+	}
+
+	prefix, err := f.r.ReadString(' ')
+	if err != nil {
+		return frame{}, fmt.Errorf("truncated length prefix")
+	}
+	size, err := strconv.Atoi(strings.TrimSuffix(prefix, " "))
+	if err != nil || size < 0 {
+		return frame{}, fmt.Errorf("invalid length prefix %q", prefix)
+	}
+
+	data := make([]byte, min(size, f.max))
+	if _, err := io.ReadFull(f.r, data); err != nil {
+		return frame{}, fmt.Errorf("truncated message of %d bytes: %v", size, err)
+	}
+	if size > f.max {
+		if _, err := io.CopyN(io.Discard, f.r, int64(size-f.max)); err != nil {
+			return frame{}, fmt.Errorf("truncated message of %d bytes: %v", size, err)
+		}
+	}
+	return frame{data: data, oversized: size > f.max}, nil
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+func TestHandleIngest(t *testing.T) {
+	inputRejects, _ = newRejects("")
+
+	tt := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		queued      int // messages already in the Stdin Channel, of capacity 3
+		terminating bool
+		status      int
+		response    ingestResponse
+		retryAfter  string
+	}{
+		{"text", http.MethodPost, "text/plain", "disk full", 0, false, http.StatusAccepted, ingestResponse{Accepted: 1}, ""},
+		{"array", http.MethodPost, "application/json", `["a", {"body": "b"}]`, 0, false, http.StatusAccepted, ingestResponse{Accepted: 2}, ""},
+		{"queue full", http.MethodPost, "application/json", `["a", "b"]`, 2, false, http.StatusServiceUnavailable, ingestResponse{Error: "queue full"}, "5"},
+		{"terminating", http.MethodPost, "text/plain", "disk full", 0, true, http.StatusServiceUnavailable, ingestResponse{Error: "terminating"}, ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := make(chan nl.Notification, 3)
+			for i := 0; i < tc.queued; i++ {
+				ch <- nl.Notification{Body: []byte("queued")}
+			}
+			in := &ingest{input: inputConfig{MaxMessageSize: 1024}, ch: ch}
+			isTerminating.Store(tc.terminating)
+			defer isTerminating.Store(false)
+
+			req := httptest.NewRequest(tc.method, ingestPath, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			in.handleIngest(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d; got %d", tc.status, rec.Code)
+			}
+			var res ingestResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+				t.Fatalf("unexpected response %q: %v", rec.Body.String(), err)
+			}
+			if res != tc.response {
+				t.Errorf("expected response %+v; got %+v", tc.response, res)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tc.retryAfter {
+				t.Errorf("expected Retry-After %q; got %q", tc.retryAfter, got)
+			}
+			if expected := tc.queued + tc.response.Accepted; len(ch) != expected {
+				t.Errorf("expected %d messages in the Stdin Channel; got %d", expected, len(ch))
+			}
+		})
+	}
+}
+
+func TestHandleIngestErrors(t *testing.T) {
+	inputRejects, _ = newRejects("")
+
+	tt := []struct {
+		name        string
+		method      string
+		contentType string
+		body        string
+		status      int
+		errMsg      string
+	}{
+		{"method not allowed", http.MethodGet, "", "", http.StatusMethodNotAllowed, "method not allowed"},
+		{"too large", http.MethodPost, "text/plain", strings.Repeat("x", 17), http.StatusRequestEntityTooLarge, "body bigger than 16 bytes"},
+		{"empty body", http.MethodPost, "text/plain", " \n", http.StatusBadRequest, "empty body"},
+		{"invalid message", http.MethodPost, "application/json", `{"text": "a"}`, http.StatusBadRequest, `invalid message: json: unknown field "text"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			in := &ingest{input: inputConfig{MaxMessageSize: 16}, ch: make(chan nl.Notification, 1)}
+			req := httptest.NewRequest(tc.method, ingestPath, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			in.handleIngest(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d; got %d", tc.status, rec.Code)
+			}
+			if got := strings.TrimSpace(rec.Body.String()); got != tc.errMsg {
+				t.Errorf("expected error %q; got %q", tc.errMsg, got)
+			}
+			if len(in.ch) != 0 {
+				t.Errorf("expected no message queued; got %d", len(in.ch))
+			}
+		})
+	}
+}
+
+func TestDecodeIngestBody(t *testing.T) {
+	tt := []struct {
+		name         string
+		body         string
+		contentType  string
+		bodies       []string
+		contentTypes []string
+		errMsg       string
+	}{
+		{"text", "disk full\n", "text/plain", []string{"disk full\n"}, []string{"text/plain"}, ""},
+		{"JSON without its content type", `{"body": "a"}`, "", []string{`{"body": "a"}`}, []string{""}, ""},
+		{"JSON object", `{"body": "a", "priority": 3}`, "application/json", []string{"a"}, []string{""}, ""},
+		{"JSON object body", `{"body": {"alert": "disk full"}}`, "application/json; charset=utf-8", []string{`{"alert": "disk full"}`}, []string{"application/json"}, ""},
+		{"JSON array", ` ["a", {"body": "b"}]`, "application/json", []string{"a", "b"}, []string{"", ""}, ""},
+		{"empty array", "[]", "application/json", nil, nil, "empty array"},
+		{"invalid array", `["a",`, "application/json", nil, nil, "unexpected end of JSON input"},
+		{"invalid element", `["a", {"body": null}]`, "application/json", nil, nil, "element 1: missing body"},
+		{"invalid object", `{"body": "a"} {}`, "application/json", nil, nil, "unexpected data after the JSON object"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			messages, err := decodeIngestBody([]byte(tc.body), tc.contentType)
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			bodies, contentTypes := []string{}, []string{}
+			for _, n := range messages {
+				bodies = append(bodies, string(n.Body))
+				contentTypes = append(contentTypes, n.ContentType)
+			}
+			if !reflect.DeepEqual(bodies, tc.bodies) {
+				t.Errorf("expected bodies %q; got %q", tc.bodies, bodies)
+			}
+			if !reflect.DeepEqual(contentTypes, tc.contentTypes) {
+				t.Errorf("expected content types %q; got %q", tc.contentTypes, contentTypes)
+			}
+		})
+	}
+}
+
+func TestSyslogFramer(t *testing.T) {
+	tt := []struct {
+		name     string
+		max      int
+		input    string
+		expected []string
+		errMsg   string
+	}{
+		{"octet counting", 100, "5 <13>a9 <13>b\nc d", []string{"<13>a", "<13>b\nc d"}, ""},
+		{"non-transparent", 100, "<13>a\r\n<13>b\n<13>c", []string{"<13>a", "<13>b", "<13>c"}, ""},
+		{"mixed", 100, "<13>a\n5 <13>b<13>c\n", []string{"<13>a", "<13>b", "<13>c"}, ""},
+		{"octet counting oversized", 3, "5 <13>a2 <1", []string{"<13+", "<1"}, ""},
+		{"non-transparent oversized", 3, "<13>a\n<1\n", []string{"<13+", "<1"}, ""},
+		{"truncated length prefix", 100, "12", []string{}, "truncated length prefix"},
+		{"invalid length prefix", 100, "1x <13>a", []string{}, `invalid length prefix "1x "`},
+		{"truncated message", 100, "10 <13>a", []string{}, "truncated message of 10 bytes: unexpected EOF"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &syslogFramer{r: bufio.NewReader(strings.NewReader(tc.input)), max: tc.max}
+			frames, err := readAllFrames(f)
+			if tc.errMsg == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || err.Error() != tc.errMsg) {
+				t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+			}
+			if !reflect.DeepEqual(frames, tc.expected) {
+				t.Errorf("expected frames %q; got %q", tc.expected, frames)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultInputFormat = "text"
+
+// inputFormats are the formats of the messages read from stdin:
+// text sends every line as the body, jsonl decodes every line as a jsonMessage
+var inputFormats = []string{"text", "jsonl"}
+
+// jsonMessage is a message of the jsonl input, e.g.
+// {"body": "disk full", "headers": {"X-Host": "db1"}, "priority": 5, "key": "db1", "ttl": "1m", "target": "alerts", "delay": "10s"}
+type jsonMessage struct {
+	Body     json.RawMessage   `json:"body"`     // a string is sent as text, any other JSON value is sent as application/json
+	Headers  map[string]string `json:"headers"`  // headers sent with the message
+	Priority int               `json:"priority"` // sent in the X-Priority header
+	Key      string            `json:"key"`      // sent in the X-Message-Key header
+	TTL      duration          `json:"ttl"`      // the message is discarded if it is not delivered within ttl since it was read
+	Target   string            `json:"target"`   // path resolved against the URL
+	Delay    duration          `json:"delay"`    // the message is not delivered before delay since it was read
+}
+
+// decoder converts a message read from stdin into a notification
+type decoder func(line []byte) (nl.Notification, error)
+
+func newDecoder(format string) decoder {
+	if format == "jsonl" {
+		return decodeJSONMessage
+	}
+	return func(line []byte) (nl.Notification, error) {
+		return nl.Notification{Body: line}, nil
+	}
+}
+
+// decodeJSONMessage decodes a jsonl line, the unknown fields are rejected. The line can not set metadata, so
+// the reserved metadata of notify (see reservedMetadata) never comes from the input.
+func decodeJSONMessage(line []byte) (nl.Notification, error) {
+	var msg jsonMessage
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&msg); err != nil {
+		return nl.Notification{}, err
+	}
+	if dec.More() {
+		return nl.Notification{}, fmt.Errorf("unexpected data after the JSON object")
+	}
+	if len(msg.Body) == 0 || string(msg.Body) == "null" {
+		return nl.Notification{}, fmt.Errorf("missing body")
+	}
+	if msg.TTL.Duration < 0 || msg.Delay.Duration < 0 {
+		return nl.Notification{}, fmt.Errorf("ttl and delay must not be negative")
+	}
+
+	n := nl.Notification{
+		Priority: msg.Priority,
+		Key:      msg.Key,
+		Path:     msg.Target,
+	}
+	var text string
+	if err := json.Unmarshal(msg.Body, &text); err == nil {
+		n.Body = []byte(text)
+	} else {
+		n.Body = msg.Body
+		n.ContentType = "application/json"
+	}
+	if len(msg.Headers) > 0 {
+		n.Header = http.Header{}
+		for key, value := range msg.Headers {
+			n.Header.Set(key, value)
+		}
+	}
+
+	now := time.Now()
+	if msg.TTL.Duration > 0 {
+		n.ExpiresAt = now.Add(msg.TTL.Duration)
+	}
+	if msg.Delay.Duration > 0 {
+		n.NotBefore = now.Add(msg.Delay.Duration)
+	}
+	return n, nil
+}
+
+// inputSink receives the messages read by readInput, numbered from 1 in the order of the input
+type inputSink interface {
+	push(num int, n nl.Notification)
+	reject(num int, data []byte, err error)
+}
+
+// channelSink inserts the messages into the Stdin Channel. The name of the input is reported with the rejected
+// messages, it is empty for stdin.
+type channelSink struct {
+	ch   chan<- nl.Notification
+	name string
+}
+
+func (s channelSink) push(num int, n nl.Notification) {
+	s.ch <- n
+}
+
+func (s channelSink) reject(num int, data []byte, err error) {
+	inputRejects.reject(s.name, num, data, err)
+}
+
+// readInput reads the messages of the source until it ends, decoding them and passing them to the sink.
+// The messages that can not be decoded, or bigger than the max size with the reject policy, are rejected.
+func readInput(source framer, input inputConfig, decode decoder, sink inputSink) error {
+	frames, errc := readFrames(source)
+
+	// with the multiline framing, a message is sent once the next one starts or no line arrives for a while
+	var join *multiline
+	if input.Framing == "multiline" {
+		join = &multiline{continuation: regexp.MustCompile(input.MultilinePattern), max: input.MaxMessageSize}
+	}
+	wait := time.NewTimer(multilineTimeout)
+	wait.Stop()
+
+	numMsg := 0
+	emit := func(f frame) {
+		numMsg++
+		// the messages not pushed are committed right away, the rest once they are queued into notilib
+		commit := f.commit
+		defer func() {
+			if commit != nil {
+				commit()
+			}
+		}()
+		if len(bytes.TrimSpace(f.data)) == 0 {
+			return
+		}
+		if f.oversized {
+			if input.Oversize == "reject" {
+				sink.reject(numMsg, f.data, fmt.Errorf("message bigger than %d bytes", input.MaxMessageSize))
+				return
+			}
+			log.Warnf("message %d truncated to %d bytes", numMsg, input.MaxMessageSize)
+		}
+
+		n, err := decode(f.data)
+		if err != nil {
+			sink.reject(numMsg, f.data, err)
+			return
+		}
+		if commit != nil {
+			n = inputCommits.register(n, commit)
+			commit = nil
+		}
+		sink.push(numMsg, n)
+	}
+
+	for {
+		select {
+		case f, ok := <-frames:
+			if !ok {
+				if join != nil {
+					if pending, ok := join.flush(); ok {
+						emit(pending)
+					}
+				}
+				return <-errc
+			}
+			if join == nil {
+				emit(f)
+				continue
+			}
+			if previous, ok := join.add(f); ok {
+				emit(previous)
+			}
+			wait.Reset(multilineTimeout)
+		case <-wait.C:
+			if pending, ok := join.flush(); ok {
+				emit(pending)
+			}
+		}
+	}
+}
+
+// rejects writes the malformed messages, as JSON lines, to a file instead of sending them
+type rejects struct {
+	mu    sync.Mutex // serializes the writes of the inputs
+	file  *os.File   // nil if no file has been configured, the rejected messages are only logged
+	count uint64
+}
+
+// rejectedMessage is a line of the rejects file
+type rejectedMessage struct {
+	Source string `json:"source,omitempty"` // input of the message, empty for stdin
+	Line   int    `json:"line,omitempty"`   // number of the message in the input, the line with the default framing
+	Error  string `json:"error"`            // reason of the rejection
+	Input  string `json:"input"`            // message as it was read, truncated to the max size
+}
+
+func newRejects(path string) (*rejects, error) {
+	if path == "" {
+		return &rejects{}, nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the rejects file: %v", err)
+	}
+	return &rejects{file: file}, nil
+}
+
+func (r *rejects) reject(source string, line int, input []byte, err error) {
+	atomic.AddUint64(&r.count, 1)
+	if line > 0 {
+		log.Warnf("message %d%s rejected: %v", line, sourceSuffix(source), err)
+	} else {
+		log.Warnf("message%s rejected: %v", sourceSuffix(source), err)
+	}
+	if r.file == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out, _ := json.Marshal(rejectedMessage{Source: source, Line: line, Error: err.Error(), Input: string(input)})
+	if _, err := r.file.Write(append(out, '\n')); err != nil {
+		log.Errorf("unable to write the rejects file: %v", err)
+	}
+}
+
+func sourceSuffix(source string) string {
+	if source == "" {
+		return ""
+	}
+	return " from " + source
+}
+
+// rejected returns the number of messages rejected so far
+func (r *rejects) rejected() uint64 {
+	return atomic.LoadUint64(&r.count)
+}
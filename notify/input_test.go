@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+func TestDecodeJSONMessage(t *testing.T) {
+	tt := []struct {
+		name     string
+		line     string
+		expected nl.Notification
+		ttl      time.Duration
+		delay    time.Duration
+		errMsg   string
+	}{
+		{"string body", `{"body":"disk full"}`, nl.Notification{Body: []byte("disk full")}, 0, 0, ""},
+		{"JSON body", `{"body":{"host":"db1","usage":97}}`, nl.Notification{Body: []byte(`{"host":"db1","usage":97}`), ContentType: "application/json"}, 0, 0, ""},
+		{"all the fields", `{"body":"disk full","headers":{"x-host":"db1"},"priority":5,"key":"db1","ttl":"1m","target":"alerts","delay":"10s"}`,
+			nl.Notification{Body: []byte("disk full"), Header: http.Header{"X-Host": {"db1"}}, Priority: 5, Key: "db1", Path: "alerts"}, time.Minute, 10 * time.Second, ""},
+		{"missing body", `{"key":"db1"}`, nl.Notification{}, 0, 0, "missing body"},
+		{"null body", `{"body":null}`, nl.Notification{}, 0, 0, "missing body"},
+		{"unknown field", `{"body":"a","host":"db1"}`, nl.Notification{}, 0, 0, `json: unknown field "host"`},
+		{"method not accepted", `{"body":"a","method":"PUT"}`, nl.Notification{}, 0, 0, `json: unknown field "method"`},
+		{"metadata not accepted", `{"body":"a","metadata":{"notify_ack":"3"}}`, nl.Notification{}, 0, 0, `json: unknown field "metadata"`},
+		{"invalid priority", `{"body":"a","priority":"high"}`, nl.Notification{}, 0, 0, "json: cannot unmarshal string into Go struct field jsonMessage.priority of type int"},
+		{"invalid ttl", `{"body":"a","ttl":"soon"}`, nl.Notification{}, 0, 0, `invalid duration "soon"`},
+		{"negative ttl", `{"body":"a","ttl":"-1m"}`, nl.Notification{}, 0, 0, "ttl and delay must not be negative"},
+		{"negative delay", `{"body":"a","delay":"-10s"}`, nl.Notification{}, 0, 0, "ttl and delay must not be negative"},
+		{"trailing data", `{"body":"a"} {"body":"b"}`, nl.Notification{}, 0, 0, "unexpected data after the JSON object"},
+		{"not an object", `disk full`, nl.Notification{}, 0, 0, "invalid character 'd' looking for beginning of value"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			before := time.Now()
+			n, err := decodeJSONMessage([]byte(tc.line))
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ttl := n.ExpiresAt.Sub(before); tc.ttl == 0 && !n.ExpiresAt.IsZero() || tc.ttl > 0 && (ttl < tc.ttl || ttl > tc.ttl+time.Second) {
+				t.Errorf("expected ttl %v; got expiration %v", tc.ttl, n.ExpiresAt)
+			}
+			if delay := n.NotBefore.Sub(before); tc.delay == 0 && !n.NotBefore.IsZero() || tc.delay > 0 && (delay < tc.delay || delay > tc.delay+time.Second) {
+				t.Errorf("expected delay %v; got not before %v", tc.delay, n.NotBefore)
+			}
+			n.ExpiresAt, n.NotBefore = time.Time{}, time.Time{}
+			if !reflect.DeepEqual(n, tc.expected) {
+				t.Errorf("expected notification %+v; got %+v", tc.expected, n)
+			}
+		})
+	}
+}
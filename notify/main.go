@@ -1,15 +1,13 @@
 package main
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -23,216 +21,536 @@ const defaultMaxNumRetrials = 2
 const defaultMaxNumMessagesToProcess = 100
 const defaultLogLevel = log.InfoLevel
 const defaultTimeout = 5 * time.Second
+const defaultMethod = "POST"
+const defaultPausedOverflow = nl.OverflowBlock
+const defaultOverflow = nl.OverflowBlock
+const defaultMessagesPerSecond = 1000
+const defaultBurst = 1000
+const defaultMessageCapacity = 1000
+const defaultErrorCapacity = 500
+const defaultDeadLetterCapacity = 1000
 
 var notilib nl.Notilib
-var conf *config
-var isTerminating = false
-var stdinChan <-chan string
-var timeout time.Duration
-
-type config struct {
-	url                     string
-	interval                time.Duration
-	channelCapacity         int
-	maxNumRetrials          int
-	maxNumMessagesToProcess int
-	logLevel                log.Level
-}
+var conf atomic.Pointer[config] // replaced on SIGHUP, see reload
+var isTerminating atomic.Bool   // set by terminate, read by the inputs and the admin handlers
+var stdinChan <-chan nl.Notification
+var inputRejects *rejects
+var inputTail *tailer   // nil when reading from stdin
+var inputIngest *ingest // nil without network inputs
 
 func main() {
-	// read parameters and arguments from flags
-	err := parseFlags()
+	// subcommands
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "test-pipeline" {
+		os.Exit(runTestPipelineCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "send" {
+		os.Exit(runSendCommand(os.Args[2:]))
+	}
+
+	// read the configuration from the defaults, the configuration file, the environment and the flags
+	c, err := parseFlags(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(exitDelivered)
+	}
 	if err != nil {
-		return
+		fmt.Println(err)
+		os.Exit(exitInvalid)
 	}
+	conf.Store(c)
+	p, _ := newPipeline(c.Pipeline)
+	inputPipeline.Store(p)
 
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
 
-	// init logger and configure signals notifications (SIGINT)
-	setup(conf.logLevel, cancel)
+	// init logger
+	initLogger(c.logLevel())
 
 	log.Infof("HTTP Notification client started. Listening for new messages from stdin...")
-	log.Debugf("Notify configuration: \n%v\n", conf)
+	log.Debugf("Notify configuration: \n%v\n", c)
 
-	// create a goroutine dedicated to read lines from stdin and send them to a channel to be processed later (each interval)
-	stdinChan = listen(os.Stdin, conf.channelCapacity, cancel)
+	inputRejects, err = newRejects(c.Input.Rejects)
+	if err != nil {
+		exitOnError(exitFailed, err)
+	}
 
-	// create a notilib instance using the default configuration (passing nil as the second parameter)
-	config := nl.DefaultConfig()
-	config.LogLevel = conf.logLevel
-	notilib, err = nl.New(conf.url, http.DefaultClient, config)
+	// create a notilib instance sharing the logger of notify
+	config := c.notilibConfig()
+	redactor = config.Redactor
+	config.Logger = nl.NewLogrusLogger(log.StandardLogger())
+	if c.Metrics.Addr != "" {
+		config.Metrics = initMetrics(c.Metrics.Addr, redactor)
+	}
+	notilib, err = nl.New(c.Endpoint.URL, c.httpClient(), config)
 	if err != nil {
-		log.Errorf("unable to start the client: %v", err)
-		return
+		exitOnError(exitInvalid, err)
 	}
 
 	// start the error handler responsible for retrials
 	initErrorHandler()
 
+	// confirm the deliveries to the unix socket clients
+	initSuccessHandler()
+
+	// answer the unix socket clients waiting for the messages cancelled or expired
+	initDiscardHandler()
+
+	// toggle the delivery on SIGUSR1
+	initPauseHandler()
+
+	// re-read the configuration on SIGHUP
+	initReloadHandler(os.Args[1:])
+
 	// start the notilib service
-	notilib.Listen(ctx)
+	if err := notilib.Listen(ctx); err != nil {
+		exitOnError(exitFailed, err)
+	}
+
+	// summarize the messages of every window
+	if c.Digest.Window.Duration > 0 {
+		inputDigest = newDigest(c.Digest)
+		go inputDigest.run()
+	}
+	if c.Batch.Mode == "stream" {
+		inputStream = newStreamer(c.Batch)
+	}
+
+	// serve the health, readiness and status endpoints
+	if c.Admin.Addr != "" {
+		initAdmin(c.Admin.Addr, c.Admin.ReadyQueueThreshold, c.Admin.Token)
+	}
+
+	// the inputs start last, once everything used by terminate is ready, as the end of the input terminates notify
+	ch := make(chan nl.Notification, c.Queue.StdinCapacity)
+	stdinChan = ch
+	var source framer
+	if len(c.Input.Tail) > 0 {
+		inputTail, err = newTailer(c.Input)
+		if err != nil {
+			exitOnError(exitFailed, err)
+		}
+		source = inputTail
+	} else if !c.Ingest.enabled() {
+		source = newFramer(os.Stdin, c.Input)
+	}
+
+	// accept messages from the network, stdin is not read and notify runs until it is terminated
+	if c.Ingest.enabled() {
+		inputIngest, err = startIngest(c.Ingest, c.Input, ch)
+		if err != nil {
+			exitOnError(exitFailed, err)
+		}
+	}
+
+	// configure signals notifications (SIGINT, SIGTERM)
+	initSignalsHandler(cancel)
+
+	// create a goroutine dedicated to read lines from stdin and send them to a channel to be processed later (each interval)
+	if source != nil {
+		listen(source, c.Input, ch, newDecoder(c.Input.Format), cancel)
+	}
+
+	// forward the messages as they arrive, notify runs until it is terminated
+	if inputStream != nil {
+		inputStream.run()
+		select {}
+	}
 
 	// process messages each 'interval'
-	tick := time.Tick(conf.interval)
+	interval := c.Rate.Interval.Duration
+	ticker := time.NewTicker(interval)
 	for {
-		<-tick
+		<-ticker.C
+
+		// the interval may have been reloaded
+		if current := conf.Load().Rate.Interval.Duration; current != interval {
+			interval = current
+			ticker.Reset(interval)
+		}
 
-		if !isTerminating {
+		if !isTerminating.Load() {
 			processMessages()
 		}
 	}
 }
 
-func setup(logLevel log.Level, cancel context.CancelFunc) {
-	// logrus configuration
-	initLogger(logLevel)
+// runConfigCommand runs the "notify config" subcommands
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Printf("usage: notify config print [--config=FILE] [<flags>]\n")
+		return 2
+	}
 
-	// configuration to handle the SIGINT termination signal
-	initSignalsHandler(cancel)
+	c, err := parseFlags(args[1:])
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if c != nil {
+		fmt.Print(c)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
 
-// listen to the stdin capturing all the messages and inserting them into the Stdin Channel
-func listen(r io.Reader, chanCap int, cancel context.CancelFunc) <-chan string {
-	ch := make(chan string, chanCap)
+// runTestPipelineCommand runs the messages of stdin, read as the input of notify, through the pipeline of the
+// configuration and prints the result of every message as a JSON line, without sending them
+func runTestPipelineCommand(args []string) int {
+	// the endpoint is not used offline
+	c, err := parseFlags(append([]string{"--url=http://localhost"}, args...))
+	if err == flag.ErrHelp {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
 
-	go func(cancel context.CancelFunc) {
-		scanner := bufio.NewScanner(r)
-		scanner.Split(bufio.ScanLines)
+	p, _ := newPipeline(c.Pipeline)
+	tester := &pipelineTester{pipeline: p, out: json.NewEncoder(os.Stdout)}
+	if err := readInput(newFramer(os.Stdin, c.Input), c.Input, newDecoder(c.Input.Format), tester); err != nil {
+		fmt.Fprintf(os.Stderr, "failed at reading stdin: %v\n", err)
+		return 1
+	}
+	return 0
+}
 
-		for scanner.Scan() {
-			line := scanner.Text()
-			ch <- line
-		}
+// exitOnError reports a failure starting the client and exits with the code, exitInvalid for the configuration
+func exitOnError(code int, err error) {
+	log.Errorf("unable to start the client: %v", err)
+	os.Exit(code)
+}
 
-		if err := scanner.Err(); err != nil {
+// listen to the input (stdin or the followed files) capturing all the messages, decoding them and inserting them
+// into the Stdin Channel. Once the input ends, the application is terminated.
+func listen(source framer, input inputConfig, ch chan<- nl.Notification, decode decoder, cancel context.CancelFunc) {
+	go func(cancel context.CancelFunc) {
+		if err := readInput(source, input, decode, channelSink{ch: ch}); err != nil {
 			log.Fatalf("failed at scanning stdin: %s", err)
-		} else {
-			log.Infof("EOF found")
-			terminate(cancel)
 		}
+		log.Infof("EOF found")
+		terminate(cancel)
 	}(cancel)
-
-	return ch
 }
 
+// processMessages forwards the messages waiting in the Stdin Channel, up to --messages unless terminating
 func processMessages() {
 	numMsgs := len(stdinChan)
 	log.Debugf("new tick. Num messages in channel: %d", numMsgs)
 
-	// control the maximal amount of messages to be procesed each interval
-	if !isTerminating && numMsgs > conf.maxNumMessagesToProcess {
-		numMsgs = conf.maxNumMessagesToProcess
+	// control the maximal amount of messages to be procesed each interval, the digest takes them all as they are
+	// sent as summaries
+	if max := conf.Load().Rate.MessagesPerInterval; !isTerminating.Load() && inputDigest == nil && numMsgs > max {
+		numMsgs = max
 	}
 
-	messages := []string{}
-	for i := 0; i < numMsgs; i++ {
-		msg, ok := <-stdinChan
-		if !ok {
-			log.Fatal("Stdin Channel is closed unexpectedly")
-		}
-		if len(msg) > 0 {
+	forwardMessages(receiveMessages(nil, numMsgs))
+}
+
+// receiveMessages appends to messages up to max messages of the Stdin Channel, without waiting for more
+func receiveMessages(messages []nl.Notification, max int) []nl.Notification {
+	for len(messages) < max {
+		select {
+		case msg, ok := <-stdinChan:
+			if !ok {
+				log.Fatal("Stdin Channel is closed unexpectedly")
+			}
 			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+	return messages
+}
+
+// forwardMessages applies the pipeline to the messages and queues them into notilib, or into the digest
+func forwardMessages(received []nl.Notification) {
+	messages := []nl.Notification{}
+	pipeline := inputPipeline.Load()
+	for _, msg := range received {
+		// reshape the message, it may be dropped by a stage
+		processed, keep, err := pipeline.process(msg)
+		if err != nil {
+			inputRejects.reject("pipeline", 0, msg.Body, err)
+			inputAcks.failed(msg, "", 0, err)
+			inputCommits.commit(msg)
+			continue
+		}
+		if !keep {
+			atomic.AddUint64(&pipelineDropped, 1)
+			inputAcks.dropped(msg)
+			inputCommits.commit(msg)
+			continue
+		}
+
+		switch {
+		case len(processed.Body) == 0:
+			inputAcks.failed(processed, "", 0, fmt.Errorf("empty message"))
+			inputCommits.commit(processed)
+		case inputDigest != nil:
+			inputDigest.add(processed, time.Now())
+		default:
+			messages = append(messages, processed)
 		}
 	}
 
 	if len(messages) == 0 {
 		log.Debugf("no new messages")
 	} else {
-		// send those messages to the notifier client
-		guid, err := notilib.Notify(messages)
+		// send those messages to the notifier client, on failure the first ones may have been queued anyway
+		guid, err := notilib.NotifyMessages(context.Background(), messages)
+		queued := len(messages)
 		if err != nil {
 			log.Errorf("notifier client has reported a failure: %v", err)
-			return
+			queued = queuedCount(err)
+			inputOutcomes.addFailed(len(messages) - queued)
+			for _, msg := range messages[queued:] {
+				inputAcks.failed(msg, "", 0, err)
+			}
+			inputCommits.forget(messages[queued:]...)
+		}
+		if queued > 0 {
+			inputOutcomes.addQueued(queued)
+			log.Infof("messages received: GUID=%s", guid)
+			inputAcks.queued(messages[:queued], guid)
+			inputCommits.commit(messages[:queued]...)
 		}
-		log.Infof("messages received: GUID=%s", guid)
 	}
 }
 
-func parseFlags() error {
-	conf = &config{}
+// parseFlags builds the configuration merging, in order of precedence, the default values, the configuration file
+// (--config or NOTIFY_CONFIG), the NOTIFY_* environment variables and the flags.
+// The merged configuration is returned with the validation error, if any, so it can be printed.
+func parseFlags(args []string) (*config, error) {
+	return parseCommandFlags(args, nil, nil)
+}
+
+// parseCommandFlags parses the flags of a subcommand: define adds its own flags and positional, if not nil,
+// receives the arguments following the flags instead of rejecting them
+func parseCommandFlags(args []string, define func(fs *flag.FlagSet), positional *[]string) (*config, error) {
+	// a first pass looks for the configuration file, the flags are applied again over the merged configuration
+	path := os.Getenv(configFileEnv)
+	first := newFlagSet(defaultConfig(), &path)
+	if define != nil {
+		define(first)
+	}
+	if err := first.Parse(args); err != nil {
+		return nil, err
+	}
+
+	c := defaultConfig()
+	if path != "" {
+		if err := c.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.applyEnv(os.Environ()); err != nil {
+		return nil, err
+	}
+
+	fs := newFlagSet(c, &path)
+	if define != nil {
+		define(fs)
+	}
+	fs.Parse(args)
+	if positional != nil {
+		*positional = fs.Args()
+	} else if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument: %s", fs.Arg(0))
+	}
+
+	if err := c.validate(); err != nil {
+		if len(args) == 0 && path == "" {
+			fs.Usage()
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+// newFlagSet defines the flags over the fields of c, a flag not provided keeps the value of the field
+func newFlagSet(c *config, path *string) *flag.FlagSet {
 	const (
-		urlFlagUsage                     = "URL where to send notifications"
+		configFlagUsage                  = "Configuration file (YAML, TOML or JSON), it can also be provided with NOTIFY_CONFIG"
+		inputFlagUsage                   = "Format of the messages read from stdin. Valid values: text, jsonl"
+		rejectsFlagUsage                 = "File where the messages that can not be decoded are written as JSON lines (only logged by default)"
+		framingFlagUsage                 = "How the input is split into messages. Valid values: lines, delimiter, nul, length, multiline"
+		delimiterFlagUsage               = "Delimiter of the messages with --framing=delimiter, escape sequences are allowed, e.g. \\x1e"
+		multilinePatternFlagUsage        = "Regular expression of the continuation lines joined to the previous message with --framing=multiline"
+		maxMessageSizeFlagUsage          = "Maximal size of a message in bytes"
+		oversizeFlagUsage                = "Behaviour with the messages bigger than --max-message-size. Valid values: truncate, reject"
+		tailFlagUsage                    = "Glob pattern of the files to follow instead of reading stdin, it can be repeated"
+		tailStateFlagUsage               = "File where the offsets of the followed files are saved, to resume after a restart (not saved by default)"
+		tailPollFlagUsage                = "Interval for checking the followed files"
+		urlFlagUsage                     = "URL where to send notifications. It can be a Go template over the message metadata, e.g. http://host/items/{{.Metadata.id}}"
+		methodFlagUsage                  = "HTTP method used for sending notifications. Valid values (case insensitive): POST, PUT, PATCH, DELETE, GET"
 		intervalFlagUsage                = "Notification interval"
 		channelCapacityFlagUsage         = "Stdin Channel capacity for reading messages from stdin"
 		maxNumRetrialsFlagUsage          = "Maximal number of retrials when receives an error sending a notification"
 		maxNumMessagesToProcessFlagUsage = "Maximal number of messages to be processed per interval"
 		logLevelFlagUsage                = "Log level. Valid values: trace, debug, info, warn, error, panic, fatal"
-		timeoutFlagUsage                 = "Timeout used for flushing Stdin Channel and Message Channel on terminate the application"
+		timeoutFlagUsage                 = "Timeout for delivering the remaining messages, retrials included, on terminate the application"
+		metricsAddrFlagUsage             = "Address where to expose the Prometheus metrics on /metrics, e.g. :9100 (disabled by default)"
+		adminAddrFlagUsage               = "Address where to expose the /healthz, /readyz and /status endpoints, e.g. localhost:9101 (disabled by default)"
+		readyQueueThresholdFlagUsage     = "Fraction of the Message Channel capacity above which /readyz reports not ready"
+		adminTokenFlagUsage              = "Token required by the /admin/ control endpoints (disabled by default)"
+		pausedOverflowFlagUsage          = "Behaviour when the Message Channel is full while paused. Valid values: block, reject, spill"
+		spillDirFlagUsage                = "Directory where the messages spill while paused with --paused-overflow=spill (temporary directory by default)"
+		ingestHTTPFlagUsage              = "Address where to accept messages on POST /messages, e.g. localhost:8080 (disabled by default)"
+		ingestTCPFlagUsage               = "Address where to accept TCP connections sending messages with --input and --framing (disabled by default)"
+		syslogUDPFlagUsage               = "Address where to accept RFC 5424 syslog messages over UDP, e.g. :514 (disabled by default)"
+		syslogTCPFlagUsage               = "Address where to accept RFC 5424 syslog messages over TCP (disabled by default)"
+		listenUnixFlagUsage              = "Path of a unix socket accepting messages with --input and --framing, answering with their GUID (disabled by default)"
+		unixAckFlagUsage                 = "Last response sent to the unix socket clients for every message. Valid values: queued, delivered"
+		redactFlagUsage                  = "Bundled detector of sensitive values to redact before sending, it can be repeated. Valid values: all, jwt, aws_secret_key, aws_access_key, email, credit_card, ipv4, ipv6"
+		redactModeFlagUsage              = "Replacement of the redacted values. Valid values: mask, hash (requires redact.salt)"
+		digestWindowFlagUsage            = "Window grouping the messages into summaries, sent instead of the messages (disabled by default)"
+		digestKeyFlagUsage               = "Key grouping the messages of a digest window. Valid values: text, fingerprint (ignoring numbers and identifiers)"
+		digestFormatFlagUsage            = "Body of the digest summaries. Valid values: text, json"
+		batchModeFlagUsage               = "When the messages are forwarded: interval takes up to --messages every --interval, stream as they arrive. Valid values: interval, stream"
+		batchSizeFlagUsage               = "Maximal number of messages forwarded at once in stream mode"
+		lingerFlagUsage                  = "Longest wait of a message for its batch to reach --batch-size in stream mode, 0 forwards it right away"
 	)
 
-	// display a usage text if no parameters
-	if len(os.Args) == 1 {
+	fs := flag.NewFlagSet("notify", flag.ContinueOnError)
+	fs.Usage = func() {
 		fmt.Printf("usage: notify --url=URL [<flags>]\n")
+		fmt.Printf("       notify test-pipeline [--config=FILE] [<flags>] < SAMPLE\n")
+		fmt.Printf("       notify send --url=URL [--file=FILE] [--output=text] [<flags>] [MESSAGE]\n")
+		fmt.Printf("       notify config print [--config=FILE] [<flags>]\n")
 		fmt.Printf("\n")
 		fmt.Printf("Flags:\n")
 		fmt.Printf("	--help			Shows context-sensitive help\n")
+		fmt.Printf("	--config=FILE		%s\n", configFlagUsage)
+		fmt.Printf("	--input=%s		%s\n", defaultInputFormat, inputFlagUsage)
+		fmt.Printf("	--rejects=FILE		%s\n", rejectsFlagUsage)
+		fmt.Printf("	--framing=%s		%s\n", defaultFraming, framingFlagUsage)
+		fmt.Printf("	--delimiter=DELIM	%s\n", delimiterFlagUsage)
+		fmt.Printf("	--multiline-pattern=%s	%s\n", defaultMultilinePattern, multilinePatternFlagUsage)
+		fmt.Printf("	--max-message-size=%d	%s\n", defaultMaxMessageSize, maxMessageSizeFlagUsage)
+		fmt.Printf("	--oversize=%s	%s\n", defaultOversize, oversizeFlagUsage)
+		fmt.Printf("	--tail=PATTERN		%s\n", tailFlagUsage)
+		fmt.Printf("	--tail-state=FILE	%s\n", tailStateFlagUsage)
+		fmt.Printf("	--tail-poll=%v		%s\n", defaultTailPoll, tailPollFlagUsage)
+		fmt.Printf("	--ingest-http=ADDR	%s\n", ingestHTTPFlagUsage)
+		fmt.Printf("	--ingest-tcp=ADDR	%s\n", ingestTCPFlagUsage)
+		fmt.Printf("	--syslog-udp=ADDR	%s\n", syslogUDPFlagUsage)
+		fmt.Printf("	--syslog-tcp=ADDR	%s\n", syslogTCPFlagUsage)
+		fmt.Printf("	--listen-unix=PATH	%s\n", listenUnixFlagUsage)
+		fmt.Printf("	--unix-ack=%s	%s\n", defaultUnixAck, unixAckFlagUsage)
+		fmt.Printf("	--redact=DETECTOR	%s\n", redactFlagUsage)
+		fmt.Printf("	--redact-mode=%s	%s\n", defaultRedactMode, redactModeFlagUsage)
+		fmt.Printf("	--digest-window=DURATION	%s\n", digestWindowFlagUsage)
+		fmt.Printf("	--digest-key=%s	%s\n", defaultDigestKey, digestKeyFlagUsage)
+		fmt.Printf("	--digest-format=%s	%s\n", defaultDigestFormat, digestFormatFlagUsage)
+		fmt.Printf("	--method=%s		%s\n", defaultMethod, methodFlagUsage)
+		fmt.Printf("	--batch-mode=%s	%s\n", defaultBatchMode, batchModeFlagUsage)
+		fmt.Printf("	--batch-size=%d	%s\n", defaultBatchSize, batchSizeFlagUsage)
+		fmt.Printf("	--linger=0s		%s\n", lingerFlagUsage)
 		fmt.Printf("	-i, --interval=%v	%s\n", defaultInterval, intervalFlagUsage)
 		fmt.Printf("	-c, --chcap=%d		%s\n", defaultChannelCapacity, channelCapacityFlagUsage)
 		fmt.Printf("	-r, --retrials=%d	%s\n", defaultMaxNumRetrials, maxNumRetrialsFlagUsage)
 		fmt.Printf("	-m, --messages=%d	%s\n", defaultMaxNumMessagesToProcess, maxNumMessagesToProcessFlagUsage)
 		fmt.Printf("	-l, --loglevel=%s	%s\n", defaultLogLevel, logLevelFlagUsage)
 		fmt.Printf("	-t, --timeout=%s	%s\n", defaultTimeout, timeoutFlagUsage)
-		return fmt.Errorf("wrong usage")
+		fmt.Printf("	--metrics-addr=ADDR	%s\n", metricsAddrFlagUsage)
+		fmt.Printf("	--admin-addr=ADDR	%s\n", adminAddrFlagUsage)
+		fmt.Printf("	--ready-queue-threshold=%v	%s\n", defaultReadyQueueThreshold, readyQueueThresholdFlagUsage)
+		fmt.Printf("	--admin-token=TOKEN	%s\n", adminTokenFlagUsage)
+		fmt.Printf("	--paused-overflow=%s	%s\n", defaultPausedOverflow, pausedOverflowFlagUsage)
+		fmt.Printf("	--spill-dir=DIR		%s\n", spillDirFlagUsage)
 	}
 
+	// define the configuration file flag
+	fs.StringVar(path, "config", *path, configFlagUsage)
+
+	// define the input format and the rejects file
+	fs.StringVar(&c.Input.Format, "input", c.Input.Format, inputFlagUsage)
+	fs.StringVar(&c.Input.Rejects, "rejects", c.Input.Rejects, rejectsFlagUsage)
+
+	// define the framing of the input
+	fs.StringVar(&c.Input.Framing, "framing", c.Input.Framing, framingFlagUsage)
+	fs.StringVar(&c.Input.Delimiter, "delimiter", c.Input.Delimiter, delimiterFlagUsage)
+	fs.StringVar(&c.Input.MultilinePattern, "multiline-pattern", c.Input.MultilinePattern, multilinePatternFlagUsage)
+	fs.IntVar(&c.Input.MaxMessageSize, "max-message-size", c.Input.MaxMessageSize, maxMessageSizeFlagUsage)
+	fs.StringVar(&c.Input.Oversize, "oversize", c.Input.Oversize, oversizeFlagUsage)
+
+	// define the files to follow
+	fs.Var(&stringList{values: &c.Input.Tail}, "tail", tailFlagUsage)
+	fs.StringVar(&c.Input.TailState, "tail-state", c.Input.TailState, tailStateFlagUsage)
+	fs.Var(&c.Input.TailPoll, "tail-poll", tailPollFlagUsage)
+
+	// define the network inputs
+	fs.StringVar(&c.Ingest.HTTP, "ingest-http", c.Ingest.HTTP, ingestHTTPFlagUsage)
+	fs.StringVar(&c.Ingest.TCP, "ingest-tcp", c.Ingest.TCP, ingestTCPFlagUsage)
+	fs.StringVar(&c.Ingest.SyslogUDP, "syslog-udp", c.Ingest.SyslogUDP, syslogUDPFlagUsage)
+	fs.StringVar(&c.Ingest.SyslogTCP, "syslog-tcp", c.Ingest.SyslogTCP, syslogTCPFlagUsage)
+	fs.StringVar(&c.Ingest.Unix, "listen-unix", c.Ingest.Unix, listenUnixFlagUsage)
+	fs.StringVar(&c.Ingest.UnixAck, "unix-ack", c.Ingest.UnixAck, unixAckFlagUsage)
+
+	// define the redaction
+	fs.Var(&stringList{values: &c.Redact.Detectors}, "redact", redactFlagUsage)
+	fs.StringVar(&c.Redact.Mode, "redact-mode", c.Redact.Mode, redactModeFlagUsage)
+
+	// define the digest mode
+	fs.Var(&c.Digest.Window, "digest-window", digestWindowFlagUsage)
+	fs.StringVar(&c.Digest.Key, "digest-key", c.Digest.Key, digestKeyFlagUsage)
+	fs.StringVar(&c.Digest.Format, "digest-format", c.Digest.Format, digestFormatFlagUsage)
+
 	// define the url flag (admits also the short alternative form)
-	flag.StringVar(&conf.url, "url", "", urlFlagUsage)
-	flag.StringVar(&conf.url, "u", "", urlFlagUsage+" (shorthand)")
+	fs.StringVar(&c.Endpoint.URL, "url", c.Endpoint.URL, urlFlagUsage)
+	fs.StringVar(&c.Endpoint.URL, "u", c.Endpoint.URL, urlFlagUsage+" (shorthand)")
+
+	// define the HTTP method flag
+	fs.StringVar(&c.Endpoint.Method, "method", c.Endpoint.Method, methodFlagUsage)
+
+	// define the batching of the messages
+	fs.StringVar(&c.Batch.Mode, "batch-mode", c.Batch.Mode, batchModeFlagUsage)
+	fs.IntVar(&c.Batch.Size, "batch-size", c.Batch.Size, batchSizeFlagUsage)
+	fs.Var(&c.Batch.Linger, "linger", lingerFlagUsage)
 
 	// define the interval flag (admits also the short alternative form)
-	flag.DurationVar(&conf.interval, "interval", defaultInterval, intervalFlagUsage)
-	flag.DurationVar(&conf.interval, "i", defaultInterval, intervalFlagUsage+" (shorthand)")
+	fs.Var(&c.Rate.Interval, "interval", intervalFlagUsage)
+	fs.Var(&c.Rate.Interval, "i", intervalFlagUsage+" (shorthand)")
 
 	// define the channel capacity flag (admits also the short alternative form)
-	flag.IntVar(&conf.channelCapacity, "chcap", defaultChannelCapacity, channelCapacityFlagUsage)
-	flag.IntVar(&conf.channelCapacity, "c", defaultChannelCapacity, channelCapacityFlagUsage+" (shorthand)")
+	fs.IntVar(&c.Queue.StdinCapacity, "chcap", c.Queue.StdinCapacity, channelCapacityFlagUsage)
+	fs.IntVar(&c.Queue.StdinCapacity, "c", c.Queue.StdinCapacity, channelCapacityFlagUsage+" (shorthand)")
 
 	// define the max number of retrials flag (admits also the short alternative form)
-	flag.IntVar(&conf.maxNumRetrials, "retrials", defaultMaxNumRetrials, maxNumRetrialsFlagUsage)
-	flag.IntVar(&conf.maxNumRetrials, "r", defaultMaxNumRetrials, maxNumRetrialsFlagUsage+" (shorthand)")
+	fs.IntVar(&c.Retry.MaxRetrials, "retrials", c.Retry.MaxRetrials, maxNumRetrialsFlagUsage)
+	fs.IntVar(&c.Retry.MaxRetrials, "r", c.Retry.MaxRetrials, maxNumRetrialsFlagUsage+" (shorthand)")
 
 	// define the max number of messages to process flag (admits also the short alternative form)
-	flag.IntVar(&conf.maxNumMessagesToProcess, "messages", defaultMaxNumMessagesToProcess, maxNumMessagesToProcessFlagUsage)
-	flag.IntVar(&conf.maxNumMessagesToProcess, "m", defaultMaxNumMessagesToProcess, maxNumMessagesToProcessFlagUsage+" (shorthand)")
+	fs.IntVar(&c.Rate.MessagesPerInterval, "messages", c.Rate.MessagesPerInterval, maxNumMessagesToProcessFlagUsage)
+	fs.IntVar(&c.Rate.MessagesPerInterval, "m", c.Rate.MessagesPerInterval, maxNumMessagesToProcessFlagUsage+" (shorthand)")
 
 	// define the log level flag (admits also the short alternative form)
-	logLevelStr := ""
-	flag.StringVar(&logLevelStr, "loglevel", "", logLevelFlagUsage)
-	flag.StringVar(&logLevelStr, "l", "", logLevelFlagUsage+" (shorthand)")
+	fs.StringVar(&c.Log.Level, "loglevel", c.Log.Level, logLevelFlagUsage)
+	fs.StringVar(&c.Log.Level, "l", c.Log.Level, logLevelFlagUsage+" (shorthand)")
 
 	// define the timeout (admits also the short alternative form)
-	flag.DurationVar(&timeout, "timeout", defaultTimeout, timeoutFlagUsage)
-	flag.DurationVar(&timeout, "t", defaultTimeout, timeoutFlagUsage+" (shorthand)")
-
-	// parse the flags previously defined
-	flag.Parse()
-
-	switch logLevelStr {
-	case "trace":
-		conf.logLevel = log.TraceLevel
-	case "debug":
-		conf.logLevel = log.DebugLevel
-	case "info":
-		conf.logLevel = log.InfoLevel
-	case "warn":
-		conf.logLevel = log.WarnLevel
-	case "error":
-		conf.logLevel = log.ErrorLevel
-	case "panic":
-		conf.logLevel = log.PanicLevel
-	case "fatal":
-		conf.logLevel = log.FatalLevel
-	default:
-		conf.logLevel = defaultLogLevel
-	}
-
-	// check that we received all mandatory parameters
-	if conf.url == "" {
-		fmt.Printf("missing URL parameter\n")
-		return fmt.Errorf("missing URL parameter")
-	}
-	return nil
+	fs.Var(&c.Timeout, "timeout", timeoutFlagUsage)
+	fs.Var(&c.Timeout, "t", timeoutFlagUsage+" (shorthand)")
+
+	// define the metrics address
+	fs.StringVar(&c.Metrics.Addr, "metrics-addr", c.Metrics.Addr, metricsAddrFlagUsage)
+
+	// define the admin address and the readiness threshold
+	fs.StringVar(&c.Admin.Addr, "admin-addr", c.Admin.Addr, adminAddrFlagUsage)
+	fs.Float64Var(&c.Admin.ReadyQueueThreshold, "ready-queue-threshold", c.Admin.ReadyQueueThreshold, readyQueueThresholdFlagUsage)
+	fs.StringVar(&c.Admin.Token, "admin-token", c.Admin.Token, adminTokenFlagUsage)
+
+	// define the behaviour while paused
+	fs.StringVar(&c.Queue.PausedOverflow, "paused-overflow", c.Queue.PausedOverflow, pausedOverflowFlagUsage)
+	fs.StringVar(&c.Queue.SpillDir, "spill-dir", c.Queue.SpillDir, spillDirFlagUsage)
+
+	return fs
 }
 
 func initSignalsHandler(cancel context.CancelFunc) {
@@ -266,6 +584,9 @@ func flushStdinChannel(timeout time.Duration) <-chan bool {
 
 	go func(ch chan bool) {
 		processMessages()
+		if inputDigest != nil {
+			inputDigest.flush()
+		}
 		continueChan <- true
 	}(continueChan)
 
@@ -274,13 +595,39 @@ func flushStdinChannel(timeout time.Duration) <-chan bool {
 
 func terminate(cancel context.CancelFunc) {
 	log.Debug("main: terminate called")
-	isTerminating = true
+	isTerminating.Store(true)
+
+	// stop following the files, their offsets are saved once the Stdin Channel is flushed
+	if inputTail != nil {
+		inputTail.stop()
+	}
+
+	// stop accepting messages from the network
+	if inputIngest != nil {
+		inputIngest.close()
+	}
+
+	// forward the batch in progress before the rest of the Stdin Channel
+	if inputStream != nil {
+		inputStream.close()
+	}
 
 	// move all remaining messages from the Stdin Channel to the Message Channel
+	timeout := conf.Load().Timeout.Duration
+	deadline := time.Now().Add(timeout)
 	<-flushStdinChannel(timeout)
 
-	// give some time to the Notify function to insert all the messages to the Message Channel
-	time.Sleep(1 * time.Second)
+	// save the offsets of the lines queued into notilib, those left in the Stdin Channel are read again on restart
+	if inputTail != nil {
+		if err := inputTail.save(); err != nil {
+			log.Warnf("unable to save the tail state: %v", err)
+		}
+	}
+
+	// wait for every message to be delivered or to fail after its last retrial
+	if !waitPending(deadline) {
+		log.Warnf("timeout occurs waiting for the messages to be delivered")
+	}
 
 	// cancellation propagation, will stop the normal behaviour from the notelib
 	log.Debug("calling context cancel function")
@@ -288,12 +635,19 @@ func terminate(cancel context.CancelFunc) {
 
 	// waits until the notelib has finished sending the last messages
 	log.Debugf("terminate process started...")
-	<-notilib.Terminate(timeout)
+	quit, err := notilib.Terminate(max(time.Until(deadline), 0))
+	if err != nil {
+		log.Warnf("unable to terminate notilib: %v", err)
+		return
+	}
+	<-quit
 	log.Debugf("terminate process finished!")
 
-	// once everything is cleaned up, exit the program
+	// once everything is cleaned up, exit the program with the result of the messages
+	summary := inputOutcomes.summary()
+	fmt.Fprintf(os.Stderr, "notify: %v\n", summary)
 	log.Debugf("exit program")
-	os.Exit(0)
+	os.Exit(summary.exitCode())
 }
 
 func initLogger(logLevel log.Level) {
@@ -304,6 +658,34 @@ func initLogger(logLevel log.Level) {
 	log.SetLevel(logLevel)
 }
 
+// initSuccessHandler reads the deliveries reported by notilib, only enabled when the unix socket clients await them
+func initSuccessHandler() {
+	successCh := notilib.GetSuccessChannel()
+	if successCh == nil {
+		return
+	}
+
+	go func() {
+		for s := range successCh {
+			inputAcks.delivered(s)
+		}
+	}()
+}
+
+// initDiscardHandler reads the messages discarded by notilib, only enabled when the unix socket clients await them
+func initDiscardHandler() {
+	discardCh := notilib.GetDiscardChannel()
+	if discardCh == nil {
+		return
+	}
+
+	go func() {
+		for d := range discardCh {
+			inputAcks.discarded(d)
+		}
+	}()
+}
+
 func initErrorHandler() {
 	errCh := notilib.GetErrorChannel()
 
@@ -314,25 +696,23 @@ func initErrorHandler() {
 				if !ok {
 					log.Fatalf("Error Channel is closed unexpectedly")
 				}
-				log.Errorf("Handling new error: [%v]", e.Error())
-
-				if e.NumRetrials < conf.maxNumRetrials {
-					// retry to send this failed notification
-					notilib.Retry(e.Content, e.GUID, e.Index, e.NumRetrials)
-				}
+				handleError(e)
 			}
 		}
 	}(errCh)
 }
 
-func (c config) String() string {
-	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("{\n"))
-	sb.WriteString(fmt.Sprintf("  url: \"%s\",\n", c.url))
-	sb.WriteString(fmt.Sprintf("  interval: \"%v\",\n", c.interval))
-	sb.WriteString(fmt.Sprintf("  channelCapacity: %d,\n", c.channelCapacity))
-	sb.WriteString(fmt.Sprintf("  maxNumRetrials: %d,\n", c.maxNumRetrials))
-	sb.WriteString(fmt.Sprintf("  maxNumMessagesToProcess: %d,\n", c.maxNumMessagesToProcess))
-	sb.WriteString(fmt.Sprintf("}\n"))
-	return sb.String()
+// handleError retries the failed notification, or moves it to the dead letters once it exhausted its retrials.
+// A notification cancelled meanwhile is neither retried nor counted as failed, it has been counted as cancelled.
+func handleError(e nl.NError) {
+	log.Errorf("Handling new error: [%v]", e.Error())
+
+	if e.NumRetrials < conf.Load().Retry.MaxRetrials {
+		// retry to send this failed notification
+		notilib.RetryNotification(e.Notification, e.GUID, e.Index, e.NumRetrials)
+	} else if notilib.DeadLetter(e) {
+		// kept to be replayed from the admin endpoint
+		inputOutcomes.addFailed(1)
+		inputAcks.failed(e.Notification, e.GUID, e.Index, fmt.Errorf("%s", e.ErrorMessage))
+	}
 }
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+// promMetrics implements the notilib Metrics interface exposing the events as Prometheus metrics
+type promMetrics struct {
+	enqueued prometheus.Counter
+	retried  prometheus.Counter
+	sent     prometheus.Counter
+	failed   *prometheus.CounterVec
+	queue    prometheus.Gauge
+	inFlight prometheus.Gauge
+	latency  *prometheus.HistogramVec
+}
+
+func newPromMetrics(reg prometheus.Registerer) *promMetrics {
+	m := &promMetrics{
+		enqueued: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "notilib_messages_enqueued_total",
+			Help: "Number of messages queued into the Message Channel.",
+		}),
+		retried: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "notilib_messages_retried_total",
+			Help: "Number of failed messages queued again into the Message Channel.",
+		}),
+		sent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "notilib_messages_sent_total",
+			Help: "Number of messages accepted by the receiver.",
+		}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "notilib_messages_failed_total",
+			Help: "Number of failed deliveries by failure class.",
+		}, []string{"class"}),
+		queue: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "notilib_queue_length",
+			Help: "Number of messages waiting in the Message Channel.",
+		}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "notilib_in_flight",
+			Help: "Number of deliveries waiting for the receiver.",
+		}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "notilib_delivery_duration_seconds",
+			Help:    "Duration of the deliveries by result (sent or failed).",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"result"}),
+	}
+	reg.MustRegister(m.enqueued, m.retried, m.sent, m.failed, m.queue, m.inFlight, m.latency)
+	return m
+}
+
+func (m *promMetrics) Enqueued(n int) {
+	m.enqueued.Add(float64(n))
+}
+
+func (m *promMetrics) Retried() {
+	m.retried.Inc()
+}
+
+func (m *promMetrics) QueueLength(n int) {
+	m.queue.Set(float64(n))
+}
+
+func (m *promMetrics) InFlight(n int) {
+	m.inFlight.Set(float64(n))
+}
+
+func (m *promMetrics) Sent(latency time.Duration) {
+	m.sent.Inc()
+	m.latency.WithLabelValues("sent").Observe(latency.Seconds())
+}
+
+func (m *promMetrics) Failed(class nl.FailureClass, latency time.Duration) {
+	m.failed.WithLabelValues(string(class)).Inc()
+	m.latency.WithLabelValues("failed").Observe(latency.Seconds())
+}
+
+// registerRedactions exposes the values redacted by every rule of the redactor
+func registerRedactions(reg prometheus.Registerer, r *nl.Redactor) {
+	for rule := range r.Counts() {
+		reg.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "notilib_redactions_total",
+			Help:        "Number of messages with sensitive values redacted by rule, counted once per message.",
+			ConstLabels: prometheus.Labels{"rule": rule},
+		}, func() float64 {
+			return float64(r.Counts()[rule])
+		}))
+	}
+}
+
+// initMetrics creates the Prometheus metrics and serves them on the /metrics endpoint of addr,
+// including the counters of the redactor if not nil
+func initMetrics(addr string, redactor *nl.Redactor) nl.Metrics {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	m := newPromMetrics(reg)
+	if redactor != nil {
+		registerRedactions(reg, redactor)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	go func() {
+		log.Infof("serving metrics on %s/metrics", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Errorf("metrics server stopped: %v", err)
+		}
+	}()
+	return m
+}
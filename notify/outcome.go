@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+// Exit codes of notify
+const (
+	exitDelivered = 0 // every message has been delivered
+	exitFailed    = 1 // some messages failed, expired or were rejected
+	exitInvalid   = 2 // invalid flags or configuration
+	exitPending   = 3 // the timeout expired with messages not delivered yet
+)
+
+// pendingPoll is the interval for checking the messages pending on terminate
+const pendingPoll = 100 * time.Millisecond
+
+// inputOutcomes counts the messages that reached a final state, the delivered and expired ones are counted by notilib
+var inputOutcomes outcomes
+
+// outcomes are the counters of the messages queued into notilib, including the summaries of the digest
+type outcomes struct {
+	queued    int64
+	failed    int64 // not queued, or not delivered after the last retrial
+	cancelled int64 // cancelled from the admin endpoint
+}
+
+func (o *outcomes) addQueued(n int) {
+	atomic.AddInt64(&o.queued, int64(n))
+}
+
+func (o *outcomes) addFailed(n int) {
+	atomic.AddInt64(&o.failed, int64(n))
+}
+
+func (o *outcomes) addCancelled(n int) {
+	atomic.AddInt64(&o.cancelled, int64(n))
+}
+
+// replayed moves the replayed dead letters from failed back to pending
+func (o *outcomes) replayed(n int) {
+	atomic.AddInt64(&o.failed, -int64(n))
+}
+
+// queuedCount returns the number of messages queued by NotifyMessages despite its error, the first ones of the batch
+func queuedCount(err error) int {
+	var qerr *nl.QueueError
+	if errors.As(err, &qerr) {
+		return qerr.Queued
+	}
+	return 0
+}
+
+// deliverySummary is printed on terminate
+type deliverySummary struct {
+	Delivered uint64
+	Failed    uint64
+	Rejected  uint64 // messages of the input that could not be decoded or processed
+	Expired   uint64
+	Cancelled uint64
+	Dropped   uint64 // messages dropped by the pipeline
+	Pending   uint64 // messages neither delivered nor failed, abandoned on exit
+}
+
+// summary returns the state of the messages received so far
+func (o *outcomes) summary() deliverySummary {
+	stats := notilib.Stats()
+	s := deliverySummary{
+		Delivered: stats.Sent,
+		Failed:    uint64(max(atomic.LoadInt64(&o.failed), 0)),
+		Rejected:  inputRejects.rejected(),
+		Expired:   stats.Expired,
+		Cancelled: uint64(atomic.LoadInt64(&o.cancelled)),
+		Dropped:   atomic.LoadUint64(&pipelineDropped),
+	}
+	pending := atomic.LoadInt64(&o.queued) - int64(s.Delivered+s.Failed+s.Expired+s.Cancelled)
+	s.Pending = uint64(max(pending, 0))
+	return s
+}
+
+func (s deliverySummary) String() string {
+	return fmt.Sprintf("%d delivered, %d failed, %d rejected, %d expired, %d cancelled, %d dropped, %d pending",
+		s.Delivered, s.Failed, s.Rejected, s.Expired, s.Cancelled, s.Dropped, s.Pending)
+}
+
+// exitCode returns exitPending if any message is pending, otherwise exitFailed if any message has not been delivered
+func (s deliverySummary) exitCode() int {
+	switch {
+	case s.Pending > 0:
+		return exitPending
+	case s.Failed > 0 || s.Rejected > 0 || s.Expired > 0:
+		return exitFailed
+	}
+	return exitDelivered
+}
+
+// waitPending waits until every message queued into notilib is delivered or failed, retrials included.
+// It returns false if the deadline expires first.
+func waitPending(deadline time.Time) bool {
+	ticker := time.NewTicker(pendingPoll)
+	defer ticker.Stop()
+	for {
+		if inputOutcomes.summary().Pending == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+func TestDeliverySummaryExitCode(t *testing.T) {
+	tt := []struct {
+		name     string
+		summary  deliverySummary
+		expected int
+	}{
+		{"no messages", deliverySummary{}, exitDelivered},
+		{"all delivered", deliverySummary{Delivered: 3}, exitDelivered},
+		{"dropped and cancelled", deliverySummary{Delivered: 1, Dropped: 2, Cancelled: 1}, exitDelivered},
+		{"failed", deliverySummary{Delivered: 2, Failed: 1}, exitFailed},
+		{"rejected", deliverySummary{Delivered: 2, Rejected: 1}, exitFailed},
+		{"expired", deliverySummary{Delivered: 2, Expired: 1}, exitFailed},
+		{"pending", deliverySummary{Delivered: 2, Failed: 1, Pending: 1}, exitPending},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.summary.exitCode(); got != tc.expected {
+				t.Errorf("expected exit code %d; got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeliverySummaryString(t *testing.T) {
+	s := deliverySummary{Delivered: 5, Failed: 1, Rejected: 2, Expired: 3, Cancelled: 4, Dropped: 6, Pending: 7}
+	expected := "5 delivered, 1 failed, 2 rejected, 3 expired, 4 cancelled, 6 dropped, 7 pending"
+	if got := s.String(); got != expected {
+		t.Errorf("expected %q; got %q", expected, got)
+	}
+}
+
+func TestQueuedCount(t *testing.T) {
+	tt := []struct {
+		name     string
+		err      error
+		expected int
+	}{
+		{"not queued", nl.ErrClosed, 0},
+		{"partially queued", &nl.QueueError{Index: 3, Queued: 2, Err: nl.ErrQueueFull}, 2},
+		{"wrapped", fmt.Errorf("notify: %w", &nl.QueueError{Index: 1, Queued: 1, Err: nl.ErrQueueFull}), 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := queuedCount(tc.err); got != tc.expected {
+				t.Errorf("expected %d messages queued; got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestCancelledAfterFailure(t *testing.T) {
+	tt := []struct {
+		name        string
+		maxRetrials int
+	}{
+		{"retrials left", 2},
+		{"retrials exhausted", 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := startTestNotilib(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+			c.Retry.MaxRetrials = tc.maxRetrials
+
+			guid, err := notilib.Notify([]string{"disk full"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			inputOutcomes.addQueued(1)
+			if err := notilib.Resume(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var e nl.NError
+			select {
+			case e = <-notilib.GetErrorChannel():
+			case <-time.After(time.Second):
+				t.Fatal("failure not reported")
+			}
+
+			// the message is cancelled before its failure is handled
+			rec := adminRequest(http.MethodPost, "/admin/cancel", `{"guid": "`+guid+`"}`)
+			if got := strings.TrimSpace(rec.Body.String()); !strings.Contains(got, `"Cancelled":1`) {
+				t.Fatalf("expected the message to be cancelled; got %s", got)
+			}
+			handleError(e)
+
+			expected := deliverySummary{Cancelled: 1}
+			if summary := inputOutcomes.summary(); summary != expected {
+				t.Errorf("expected summary %v; got %v", expected, summary)
+			}
+			if letters := notilib.DeadLetters(); len(letters) != 0 {
+				t.Errorf("expected no dead letters; got %v", letters)
+			}
+		})
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package main
+
+// initPauseHandler does nothing without SIGUSR1, the delivery is paused and resumed with the admin API
+func initPauseHandler() {}
@@ -0,0 +1,31 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// initPauseHandler pauses the delivery when receiving SIGUSR1, and resumes it on the next one
+func initPauseHandler() {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1)
+
+	go func() {
+		for range sigs {
+			var err error
+			if notilib.Stats().Paused {
+				err = notilib.Resume()
+			} else {
+				err = notilib.Pause()
+			}
+			if err != nil {
+				log.Warnf("unable to toggle the delivery: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"text/template"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+// defaultRedactReplacement replaces the matches of the redact stages without a replacement
+const defaultRedactReplacement = "***"
+
+// stageTypes are the processors of the pipeline:
+// filter keeps only the messages matching the pattern, drop_if_match drops them, regex_extract sets the named
+// groups of the pattern as metadata, json_extract sets the fields of a JSON object as metadata, template renders
+// a new body, redact replaces the matches of the pattern and add_fields sets static metadata
+var stageTypes = []string{"filter", "drop_if_match", "regex_extract", "json_extract", "template", "redact", "add_fields"}
+
+// inputPipeline is the pipeline applied to the messages before queueing them into notilib, replaced on reload
+var inputPipeline atomic.Pointer[pipeline]
+
+// pipelineDropped counts the messages dropped by the pipeline
+var pipelineDropped uint64
+
+// reservedMetadata are the metadata keys tracking the messages of the unix socket clients and of the followed
+// files, the stages can not set them
+var reservedMetadata = []string{ackMetadataKey, commitMetadataKey}
+
+func reservedKey(key string) bool {
+	for _, reserved := range reservedMetadata {
+		if key == reserved {
+			return true
+		}
+	}
+	return false
+}
+
+// stageConfig is a processor of the pipeline, every type uses some of the fields
+type stageConfig struct {
+	Type        string            `yaml:"type" toml:"type" json:"type"`
+	Field       string            `yaml:"field" toml:"field" json:"field"`                      // metadata read instead of the body, by filter, drop_if_match, regex_extract, json_extract and redact
+	Pattern     string            `yaml:"pattern" toml:"pattern" json:"pattern"`                // regular expression of filter, drop_if_match, regex_extract and redact
+	Replacement string            `yaml:"replacement" toml:"replacement" json:"replacement"`    // replacement of redact, it can refer to the groups of the pattern, e.g. ${1}
+	Template    string            `yaml:"template" toml:"template" json:"template"`             // Go template of the new body, over .Body, .ContentType and .Metadata
+	ContentType string            `yaml:"content_type" toml:"content_type" json:"content_type"` // content type of the body rendered by template, kept if empty
+	Fields      map[string]string `yaml:"fields" toml:"fields" json:"fields"`                   // metadata set by add_fields
+}
+
+// stage transforms a message, it returns false when the message has to be dropped
+type stage func(n nl.Notification) (nl.Notification, bool, error)
+
+// pipeline applies its stages in order
+type pipeline struct {
+	stages []stage
+	names  []string // type of every stage, for the errors
+}
+
+// newPipeline builds the stages of the configuration, validating their patterns and templates
+func newPipeline(stages []stageConfig) (*pipeline, error) {
+	p := &pipeline{}
+	for i, c := range stages {
+		s, err := newStage(c)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d (%s): %v", i+1, c.Type, err)
+		}
+		p.stages = append(p.stages, s)
+		p.names = append(p.names, c.Type)
+	}
+	return p, nil
+}
+
+func newStage(c stageConfig) (stage, error) {
+	var re *regexp.Regexp
+	switch c.Type {
+	case "filter", "drop_if_match", "regex_extract", "redact":
+		if c.Pattern == "" {
+			return nil, fmt.Errorf("missing pattern")
+		}
+		var err error
+		if re, err = regexp.Compile(c.Pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.Type {
+	case "filter":
+		return matchStage(c.Field, re, true), nil
+	case "drop_if_match":
+		return matchStage(c.Field, re, false), nil
+	case "regex_extract":
+		return regexExtractStage(c.Field, re)
+	case "json_extract":
+		return jsonExtractStage(c.Field), nil
+	case "template":
+		return templateStage(c.Template, c.ContentType)
+	case "redact":
+		replacement := c.Replacement
+		if replacement == "" {
+			replacement = defaultRedactReplacement
+		}
+		if reservedKey(c.Field) {
+			return nil, fmt.Errorf("reserved metadata %q", c.Field)
+		}
+		return redactStage(c.Field, re, replacement), nil
+	case "add_fields":
+		if len(c.Fields) == 0 {
+			return nil, fmt.Errorf("missing fields")
+		}
+		for key := range c.Fields {
+			if reservedKey(key) {
+				return nil, fmt.Errorf("reserved metadata %q", key)
+			}
+		}
+		return addFieldsStage(c.Fields), nil
+	}
+	return nil, fmt.Errorf("invalid type %q, valid values: %s", c.Type, strings.Join(stageTypes, ", "))
+}
+
+// process applies the stages to the message, it returns false when a stage drops it
+func (p *pipeline) process(n nl.Notification) (nl.Notification, bool, error) {
+	if len(p.stages) == 0 {
+		return n, true, nil
+	}
+
+	// the stages modify a copy of the metadata
+	metadata := make(map[string]string, len(n.Metadata))
+	for key, value := range n.Metadata {
+		metadata[key] = value
+	}
+	n.Metadata = metadata
+
+	for i, s := range p.stages {
+		var keep bool
+		var err error
+		n, keep, err = s(n)
+		if err != nil {
+			return n, false, fmt.Errorf("stage %d (%s): %v", i+1, p.names[i], err)
+		}
+		if !keep {
+			return n, false, nil
+		}
+	}
+	return n, true, nil
+}
+
+// stageInput returns the body, or the metadata field if set
+func stageInput(n nl.Notification, field string) []byte {
+	if field == "" {
+		return n.Body
+	}
+	return []byte(n.Metadata[field])
+}
+
+// matchStage keeps the messages matching re when keep is set, otherwise it drops them
+func matchStage(field string, re *regexp.Regexp, keep bool) stage {
+	return func(n nl.Notification) (nl.Notification, bool, error) {
+		return n, re.Match(stageInput(n, field)) == keep, nil
+	}
+}
+
+// regexExtractStage sets the named groups of re as metadata, the messages not matching are kept as they are
+func regexExtractStage(field string, re *regexp.Regexp) (stage, error) {
+	names := re.SubexpNames()
+	named := false
+	for _, name := range names {
+		if reservedKey(name) {
+			return nil, fmt.Errorf("reserved metadata %q", name)
+		}
+		named = named || name != ""
+	}
+	if !named {
+		return nil, fmt.Errorf("the pattern has no named groups, e.g. (?P<level>\\w+)")
+	}
+
+	return func(n nl.Notification) (nl.Notification, bool, error) {
+		match := re.FindSubmatch(stageInput(n, field))
+		for i, name := range names {
+			if name != "" && match != nil {
+				n.Metadata[name] = string(match[i])
+			}
+		}
+		return n, true, nil
+	}, nil
+}
+
+// jsonExtractStage sets the fields of a JSON object as metadata, the values that are not strings are set as JSON.
+// The fields named as a reserved metadata are skipped.
+func jsonExtractStage(field string) stage {
+	return func(n nl.Notification) (nl.Notification, bool, error) {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(stageInput(n, field), &fields); err != nil {
+			return n, false, fmt.Errorf("invalid JSON object: %v", err)
+		}
+		for key, raw := range fields {
+			if reservedKey(key) {
+				continue
+			}
+			var text string
+			if err := json.Unmarshal(raw, &text); err == nil {
+				n.Metadata[key] = text
+				continue
+			}
+			var compact bytes.Buffer
+			json.Compact(&compact, raw)
+			n.Metadata[key] = compact.String()
+		}
+		return n, true, nil
+	}
+}
+
+// templateData are the fields of the message available to the templates
+type templateData struct {
+	Body        string
+	ContentType string
+	Metadata    map[string]string
+}
+
+// templateStage renders the body, a missing metadata key is an error
+func templateStage(text, contentType string) (stage, error) {
+	if text == "" {
+		return nil, fmt.Errorf("missing template")
+	}
+	tmpl, err := template.New("body").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(n nl.Notification) (nl.Notification, bool, error) {
+		var body bytes.Buffer
+		data := templateData{Body: string(n.Body), ContentType: n.ContentType, Metadata: n.Metadata}
+		if err := tmpl.Execute(&body, data); err != nil {
+			return n, false, err
+		}
+		n.Body = body.Bytes()
+		if contentType != "" {
+			n.ContentType = contentType
+		}
+		return n, true, nil
+	}, nil
+}
+
+// redactStage replaces the matches of re in the body, or the metadata field if set
+func redactStage(field string, re *regexp.Regexp, replacement string) stage {
+	return func(n nl.Notification) (nl.Notification, bool, error) {
+		redacted := re.ReplaceAll(stageInput(n, field), []byte(replacement))
+		if field == "" {
+			n.Body = redacted
+		} else if _, ok := n.Metadata[field]; ok {
+			n.Metadata[field] = string(redacted)
+		}
+		return n, true, nil
+	}
+}
+
+// addFieldsStage sets the fields as metadata, replacing the existing ones
+func addFieldsStage(fields map[string]string) stage {
+	return func(n nl.Notification) (nl.Notification, bool, error) {
+		for key, value := range fields {
+			n.Metadata[key] = value
+		}
+		return n, true, nil
+	}
+}
+
+// pipelineResult is a JSON line printed by notify test-pipeline
+type pipelineResult struct {
+	Line        int               `json:"line"`   // number of the message in the input
+	Status      string            `json:"status"` // kept, dropped or rejected
+	Body        string            `json:"body,omitempty"`
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// pipelineTester is the inputSink of notify test-pipeline, printing the result of the pipeline for every message
+type pipelineTester struct {
+	pipeline *pipeline
+	out      *json.Encoder
+}
+
+func (t *pipelineTester) push(num int, n nl.Notification) {
+	processed, keep, err := t.pipeline.process(n)
+	switch {
+	case err != nil:
+		t.reject(num, n.Body, err)
+	case !keep:
+		t.out.Encode(pipelineResult{Line: num, Status: "dropped"})
+	case len(processed.Body) == 0:
+		t.reject(num, n.Body, fmt.Errorf("empty message"))
+	default:
+		t.out.Encode(pipelineResult{
+			Line:        num,
+			Status:      "kept",
+			Body:        string(processed.Body),
+			ContentType: processed.ContentType,
+			Metadata:    processed.Metadata,
+		})
+	}
+}
+
+func (t *pipelineTester) reject(num int, data []byte, err error) {
+	t.out.Encode(pipelineResult{Line: num, Status: "rejected", Error: err.Error()})
+}
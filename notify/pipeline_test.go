@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+func TestStages(t *testing.T) {
+	tt := []struct {
+		name     string
+		stage    stageConfig
+		in       nl.Notification
+		keep     bool
+		body     string
+		metadata map[string]string
+		errMsg   string
+	}{
+		{"filter matching", stageConfig{Type: "filter", Pattern: "ERROR"}, nl.Notification{Body: []byte("ERROR disk full")}, true, "ERROR disk full", map[string]string{}, ""},
+		{"filter not matching", stageConfig{Type: "filter", Pattern: "ERROR"}, nl.Notification{Body: []byte("INFO started")}, false, "INFO started", map[string]string{}, ""},
+		{"filter on a field", stageConfig{Type: "filter", Field: "level", Pattern: "^error$"}, nl.Notification{Body: []byte("x"), Metadata: map[string]string{"level": "error"}}, true, "x", map[string]string{"level": "error"}, ""},
+		{"drop_if_match matching", stageConfig{Type: "drop_if_match", Pattern: "healthcheck"}, nl.Notification{Body: []byte("GET /healthcheck")}, false, "GET /healthcheck", map[string]string{}, ""},
+		{"drop_if_match not matching", stageConfig{Type: "drop_if_match", Pattern: "healthcheck"}, nl.Notification{Body: []byte("GET /users")}, true, "GET /users", map[string]string{}, ""},
+		{"regex_extract", stageConfig{Type: "regex_extract", Pattern: `^(?P<level>\w+) (?P<service>\S+): (?P<text>.*)$`}, nl.Notification{Body: []byte("WARN db1: slow query")}, true, "WARN db1: slow query", map[string]string{"level": "WARN", "service": "db1", "text": "slow query"}, ""},
+		{"regex_extract not matching", stageConfig{Type: "regex_extract", Pattern: `^(?P<level>\w+):`}, nl.Notification{Body: []byte("no level")}, true, "no level", map[string]string{}, ""},
+		{"json_extract", stageConfig{Type: "json_extract"}, nl.Notification{Body: []byte(`{"host": "db1", "cpu": 97.5, "tags": ["a", "b"]}`)}, true, `{"host": "db1", "cpu": 97.5, "tags": ["a", "b"]}`, map[string]string{"host": "db1", "cpu": "97.5", "tags": `["a","b"]`}, ""},
+		{"json_extract invalid", stageConfig{Type: "json_extract"}, nl.Notification{Body: []byte(`not json`)}, false, "", nil, "stage 1 (json_extract): invalid JSON object: invalid character 'o' in literal null (expecting 'u')"},
+		{"template", stageConfig{Type: "template", Template: `{"host":"{{.Metadata.host}}","text":{{printf "%q" .Body}}}`, ContentType: "application/json"}, nl.Notification{Body: []byte("disk full"), Metadata: map[string]string{"host": "db1"}}, true, `{"host":"db1","text":"disk full"}`, map[string]string{"host": "db1"}, ""},
+		{"template missing key", stageConfig{Type: "template", Template: "{{.Metadata.host}}"}, nl.Notification{Body: []byte("disk full")}, false, "", nil, `stage 1 (template): template: body:1:11: executing "body" at <.Metadata.host>: map has no entry for key "host"`},
+		{"redact", stageConfig{Type: "redact", Pattern: `token=\S+`}, nl.Notification{Body: []byte("login token=abc123 ok")}, true, "login *** ok", map[string]string{}, ""},
+		{"redact with groups", stageConfig{Type: "redact", Pattern: `(token=)\S+`, Replacement: "${1}xxx"}, nl.Notification{Body: []byte("token=abc123")}, true, "token=xxx", map[string]string{}, ""},
+		{"redact a field", stageConfig{Type: "redact", Field: "user", Pattern: `@.*`}, nl.Notification{Body: []byte("x"), Metadata: map[string]string{"user": "bob@example.com"}}, true, "x", map[string]string{"user": "bob***"}, ""},
+		{"add_fields", stageConfig{Type: "add_fields", Fields: map[string]string{"env": "prod"}}, nl.Notification{Body: []byte("x"), Metadata: map[string]string{"env": "dev", "host": "db1"}}, true, "x", map[string]string{"env": "prod", "host": "db1"}, ""},
+		{"json_extract reserved metadata", stageConfig{Type: "json_extract"}, nl.Notification{Body: []byte(`{"notify_ack": "3", "notify_commit": "1", "host": "db1"}`), Metadata: map[string]string{ackMetadataKey: "7"}}, true, `{"notify_ack": "3", "notify_commit": "1", "host": "db1"}`, map[string]string{ackMetadataKey: "7", "host": "db1"}, ""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := newPipeline([]stageConfig{tc.stage})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			in := tc.in
+			original := len(in.Metadata)
+
+			n, keep, err := p.process(in)
+			if err != nil {
+				if err.Error() != tc.errMsg {
+					t.Errorf("expected error message: %s; got: %v", tc.errMsg, err)
+				}
+				return
+			}
+			if tc.errMsg != "" {
+				t.Fatalf("expected error message: %s; got none", tc.errMsg)
+			}
+			if keep != tc.keep {
+				t.Errorf("expected keep %v; got %v", tc.keep, keep)
+			}
+			if string(n.Body) != tc.body {
+				t.Errorf("expected body %q; got %q", tc.body, n.Body)
+			}
+			if !reflect.DeepEqual(n.Metadata, tc.metadata) {
+				t.Errorf("expected metadata %v; got %v", tc.metadata, n.Metadata)
+			}
+			if len(in.Metadata) != original {
+				t.Errorf("the metadata of the input has been modified: %v", in.Metadata)
+			}
+		})
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	tt := []struct {
+		name   string
+		stages []stageConfig
+		errMsg string
+	}{
+		{"empty", nil, ""},
+		{"invalid type", []stageConfig{{Type: "uppercase"}}, "stage 1 (uppercase): invalid type \"uppercase\", valid values: filter, drop_if_match, regex_extract, json_extract, template, redact, add_fields"},
+		{"missing pattern", []stageConfig{{Type: "filter", Pattern: "a"}, {Type: "redact"}}, "stage 2 (redact): missing pattern"},
+		{"invalid pattern", []stageConfig{{Type: "drop_if_match", Pattern: "("}}, "stage 1 (drop_if_match): error parsing regexp: missing closing ): `(`"},
+		{"no named groups", []stageConfig{{Type: "regex_extract", Pattern: `(\w+)`}}, "stage 1 (regex_extract): the pattern has no named groups, e.g. (?P<level>\\w+)"},
+		{"invalid template", []stageConfig{{Type: "template", Template: "{{.Body"}}, "stage 1 (template): template: body:1: unclosed action"},
+		{"missing fields", []stageConfig{{Type: "add_fields"}}, "stage 1 (add_fields): missing fields"},
+		{"add_fields reserved metadata", []stageConfig{{Type: "add_fields", Fields: map[string]string{"notify_ack": "1"}}}, `stage 1 (add_fields): reserved metadata "notify_ack"`},
+		{"regex_extract reserved metadata", []stageConfig{{Type: "regex_extract", Pattern: `(?P<notify_commit>\d+)`}}, `stage 1 (regex_extract): reserved metadata "notify_commit"`},
+		{"redact reserved metadata", []stageConfig{{Type: "redact", Field: "notify_ack", Pattern: "."}}, `stage 1 (redact): reserved metadata "notify_ack"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newPipeline(tc.stages)
+			if tc.errMsg == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || err.Error() != tc.errMsg) {
+				t.Errorf("expected error message: %s; got: %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestPipelineOrder(t *testing.T) {
+	p, err := newPipeline([]stageConfig{
+		{Type: "drop_if_match", Pattern: "DEBUG"},
+		{Type: "regex_extract", Pattern: `^(?P<level>\w+) (?P<text>.*)$`},
+		{Type: "redact", Field: "text", Pattern: `\d{4}-\d{4}`},
+		{Type: "add_fields", Fields: map[string]string{"env": "prod"}},
+		{Type: "template", Template: "[{{.Metadata.env}}] {{.Metadata.level}}: {{.Metadata.text}}"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, keep, _ := p.process(nl.Notification{Body: []byte("DEBUG card 1234-5678")}); keep {
+		t.Errorf("expected the DEBUG message to be dropped")
+	}
+	n, keep, err := p.process(nl.Notification{Body: []byte("ERROR card 1234-5678 declined")})
+	if err != nil || !keep {
+		t.Fatalf("expected the message to be kept; got keep %v, error %v", keep, err)
+	}
+	if expected := "[prod] ERROR: card *** declined"; string(n.Body) != expected {
+		t.Errorf("expected body %q; got %q", expected, n.Body)
+	}
+}
+
+func TestPipelineTester(t *testing.T) {
+	p, err := newPipeline([]stageConfig{
+		{Type: "drop_if_match", Pattern: "^#"},
+		{Type: "json_extract"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var out bytes.Buffer
+	tester := &pipelineTester{pipeline: p, out: json.NewEncoder(&out)}
+
+	input := inputConfig{Format: "text", Framing: "lines", MaxMessageSize: 100, Oversize: "reject"}
+	source := newFramer(bytes.NewBufferString("{\"host\":\"db1\"}\n# comment\nnot json\n"), input)
+	if err := readInput(source, input, newDecoder(input.Format), tester); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []pipelineResult{
+		{Line: 1, Status: "kept", Body: `{"host":"db1"}`, Metadata: map[string]string{"host": "db1"}},
+		{Line: 2, Status: "dropped"},
+		{Line: 3, Status: "rejected", Error: "stage 2 (json_extract): invalid JSON object: invalid character 'o' in literal null (expecting 'u')"},
+	}
+	dec := json.NewDecoder(&out)
+	for _, e := range expected {
+		var got pipelineResult
+		if err := dec.Decode(&got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(got, e) {
+			t.Errorf("expected %+v; got %+v", e, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+const defaultRedactMode = "mask"
+
+var redactModes = []string{"mask", "hash"}
+
+// allDetectors enables every bundled detector
+const allDetectors = "all"
+
+// redactor replaces the sensitive values of the messages before they are sent, nil if disabled
+var redactor *nl.Redactor
+
+// redactConfig are the detectors and rules of the redaction applied by notilib before sending the messages
+type redactConfig struct {
+	Detectors []string           `yaml:"detectors" toml:"detectors" json:"detectors"` // bundled detectors, see detectors, or all
+	Rules     []redactRuleConfig `yaml:"rules" toml:"rules" json:"rules"`             // custom rules, applied after the detectors
+	Mode      string             `yaml:"mode" toml:"mode" json:"mode"`                // mask or hash
+	Salt      string             `yaml:"salt" toml:"salt" json:"salt"`                // prepended to the values hashed, required by the hash mode
+}
+
+// redactRuleConfig is a custom redaction rule, only the first group of the pattern is replaced if it has groups
+type redactRuleConfig struct {
+	Name    string `yaml:"name" toml:"name" json:"name"`
+	Pattern string `yaml:"pattern" toml:"pattern" json:"pattern"`
+	Mask    string `yaml:"mask" toml:"mask" json:"mask"` // replacement of the mask mode, [NAME] by default
+}
+
+// enabled reports whether any detector or rule is configured
+func (c redactConfig) enabled() bool {
+	return len(c.Detectors) > 0 || len(c.Rules) > 0
+}
+
+// detectors returns the names of the bundled detectors
+func detectors() []string {
+	var names []string
+	for _, rule := range nl.DefaultRedactionRules() {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// redactor builds the redactor of the configuration, nil if disabled
+func (c redactConfig) redactor() (*nl.Redactor, error) {
+	if !c.enabled() {
+		return nil, nil
+	}
+
+	// the bundled detectors keep their order, so the longer values are redacted as a whole
+	selected := map[string]bool{}
+	for _, name := range c.Detectors {
+		if name != allDetectors && !validOption(name, detectors()) {
+			return nil, fmt.Errorf("invalid detector %q, valid values: %s, %s", name, allDetectors, strings.Join(detectors(), ", "))
+		}
+		selected[name] = true
+	}
+	var rules []nl.RedactionRule
+	for _, rule := range nl.DefaultRedactionRules() {
+		if selected[allDetectors] || selected[rule.Name] {
+			rules = append(rules, rule)
+		}
+	}
+
+	for i, r := range c.Rules {
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("rule %d (%s): missing pattern", i+1, r.Name)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %v", i+1, r.Name, err)
+		}
+		rules = append(rules, nl.RedactionRule{Name: r.Name, Pattern: re, Mask: r.Mask})
+	}
+
+	mode := nl.RedactMask
+	switch c.Mode {
+	case "mask":
+	case "hash":
+		if c.Salt == "" {
+			return nil, fmt.Errorf("the hash mode requires a salt")
+		}
+		mode = nl.RedactHash
+	default:
+		return nil, fmt.Errorf("invalid mode %q, valid values: %s", c.Mode, strings.Join(redactModes, ", "))
+	}
+	return nl.NewRedactor(mode, c.Salt, rules...)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// restartFields are the settings that can not be changed on a running instance, they keep their value on reload
+var restartFields = []string{
+	"input.",
+	"ingest.",
+	"redact.",
+	"digest.",
+	"retry.dead_letter_capacity",
+	"rate.burst",
+	"batch.",
+	"queue.",
+	"metrics.",
+	"admin.",
+}
+
+// change is a setting modified by a reload, with the secrets masked
+type change struct {
+	path     string
+	old, new interface{}
+}
+
+func (c change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.path, c.old, c.new)
+}
+
+// initReloadHandler re-reads the configuration when receiving SIGHUP, see reload
+func initReloadHandler(args []string) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGHUP)
+
+	go func() {
+		for range sigs {
+			if err := reload(args); err != nil {
+				log.Errorf("configuration not reloaded, keeping the current one: %v", err)
+			}
+		}
+	}()
+}
+
+// reload merges again the defaults, the configuration file, the environment and the flags (args), and applies
+// the changes to the running notilib instance. The queued messages are kept.
+// An invalid configuration is rejected as a whole; the settings that require a restart keep their current value.
+// Every change is validated by parseFlags before any is applied, so a failed reload leaves notify unchanged.
+func reload(args []string) error {
+	current := conf.Load()
+	next, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	changes := diffConfig(current, next)
+	if len(changes) == 0 {
+		log.Info("configuration reloaded, no changes")
+		return nil
+	}
+
+	var applied []change
+	for _, c := range changes {
+		if requiresRestart(c.path) {
+			log.Warnf("configuration change ignored, it requires a restart: %v", c)
+			continue
+		}
+		applied = append(applied, c)
+	}
+	keepRestartFields(current, next)
+
+	// the endpoint is changed first, as it is the only change that can still fail (once notilib is terminating)
+	if changed(applied, "endpoint.url", "endpoint.method", "endpoint.timeout") {
+		if err := notilib.SetEndpoint(next.Endpoint.URL, next.Endpoint.Method, next.httpClient()); err != nil {
+			return fmt.Errorf("unable to change the endpoint: %v", err)
+		}
+	}
+	if changed(applied, "endpoint.headers", "auth.") {
+		notilib.SetHeader(next.header())
+	}
+	if changed(applied, "rate.messages_per_second") {
+		// the configuration has been validated, so the rate is valid
+		notilib.SetRate(next.Rate.MessagesPerSecond)
+	}
+	if changed(applied, "pipeline") {
+		// the configuration has been validated, so the pipeline is valid
+		p, _ := newPipeline(next.Pipeline)
+		inputPipeline.Store(p)
+	}
+	if changed(applied, "log.level") {
+		if err := notilib.SetLogLevel(next.logLevel()); err != nil {
+			// notilib uses its own logger, only the one of notify is changed
+			log.SetLevel(next.logLevel())
+		}
+	}
+
+	// the retry policy, the interval and the timeout are read from the configuration when needed
+	conf.Store(next)
+	for _, c := range applied {
+		log.Infof("configuration changed: %v", c)
+	}
+	return nil
+}
+
+// diffConfig returns the settings that differ between a and b, in the order of the configuration
+func diffConfig(a, b *config) []change {
+	fields := func(c *config) map[string]interface{} {
+		m := map[string]interface{}{}
+		walkFields(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.Value) {
+			m[path] = field.Interface()
+		})
+		return m
+	}
+	maskedA, maskedB := a.masked(), b.masked()
+	oldValues, newValues := fields(&maskedA), fields(&maskedB)
+	aValues := fields(a)
+
+	var changes []change
+	walkFields(reflect.ValueOf(b).Elem(), "", func(path string, field reflect.Value) {
+		// compare the actual values, so a changed secret is detected although it is printed masked
+		if !reflect.DeepEqual(aValues[path], field.Interface()) {
+			changes = append(changes, change{path: path, old: oldValues[path], new: newValues[path]})
+		}
+	})
+	return changes
+}
+
+// keepRestartFields copies into next the current value of the settings that require a restart
+func keepRestartFields(current, next *config) {
+	values := map[string]reflect.Value{}
+	walkFields(reflect.ValueOf(current).Elem(), "", func(path string, field reflect.Value) {
+		values[path] = field
+	})
+	walkFields(reflect.ValueOf(next).Elem(), "", func(path string, field reflect.Value) {
+		if requiresRestart(path) {
+			field.Set(values[path])
+		}
+	})
+}
+
+func requiresRestart(path string) bool {
+	return hasPrefix(path, restartFields...)
+}
+
+// changed returns true if any of the changes matches one of the paths, a path ending with "." matches a section
+func changed(changes []change, paths ...string) bool {
+	for _, c := range changes {
+		if hasPrefix(c.path, paths...) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(path string, prefixes ...string) bool {
+	for _, prefix := range prefixes {
+		if path == prefix || (strings.HasSuffix(prefix, ".") && strings.HasPrefix(path, prefix)) {
+			return true
+		}
+	}
+	return false
+}
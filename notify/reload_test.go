@@ -0,0 +1,177 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes the configuration file read by reload, returning the arguments pointing to it
+func writeConfigFile(t *testing.T, path, content string) []string {
+	t.Helper()
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return []string{"--config=" + path}
+}
+
+func TestReload(t *testing.T) {
+	current := startTestNotilib(t, nil)
+	received := make(chan string, 1)
+	next := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer next.Close()
+
+	path := filepath.Join(t.TempDir(), "notify.yaml")
+	args := writeConfigFile(t, path, `
+endpoint:
+  url: `+next.URL+`
+  headers:
+    X-Source: notify
+rate:
+  messages_per_second: 50
+retry:
+  max_retrials: 5
+  dead_letter_capacity: 10
+queue:
+  message_capacity: 10
+admin:
+  addr: localhost:9101
+`)
+	if err := reload(args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := conf.Load()
+	if c.Endpoint.URL != next.URL || c.Endpoint.Headers["X-Source"] != "notify" || c.Rate.MessagesPerSecond != 50 || c.Retry.MaxRetrials != 5 {
+		t.Errorf("expected the live settings to be changed; got %+v", c)
+	}
+	// the settings that require a restart keep their current value
+	if c.Retry.DeadLetterCapacity != current.Retry.DeadLetterCapacity || c.Queue.MessageCapacity != current.Queue.MessageCapacity || c.Admin.Addr != current.Admin.Addr {
+		t.Errorf("expected the restart settings to be kept; got %+v", c)
+	}
+
+	// the queued messages are delivered to the new endpoint
+	if _, err := notilib.Notify([]string{"disk full"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := notilib.Resume(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	select {
+	case body := <-received:
+		if body != "disk full" {
+			t.Errorf("expected body %q; got %q", "disk full", body)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered to the new endpoint")
+	}
+
+	// reloading the same configuration only warns again about the settings that require a restart
+	if err := reload(args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(conf.Load(), c) {
+		t.Errorf("expected the configuration to be kept; got %+v", conf.Load())
+	}
+}
+
+func TestReloadRejected(t *testing.T) {
+	tt := []struct {
+		name      string
+		content   string
+		terminate bool
+		errMsg    string
+	}{
+		{"invalid configuration", "endpoint:\n  url: http://localhost:9999/api\nrate:\n  messages_per_second: 0\n", false, "invalid configuration: rate.messages_per_second: must be between 1 and 1000000000, got 0"},
+		{"unreadable configuration", "rate: [\n", false, "yaml: line 1: did not find expected node content"},
+		{"endpoint not changed", "endpoint:\n  url: http://localhost:9999/api\nrate:\n  messages_per_second: 50\nlog:\n  level: debug\n", true, "unable to change the endpoint: the application is terminating, it does not accept new notifications"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			current := startTestNotilib(t, nil)
+			if tc.terminate {
+				quit, err := notilib.Terminate(time.Second)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				<-quit
+			}
+
+			args := writeConfigFile(t, filepath.Join(t.TempDir(), "notify.yaml"), tc.content)
+			err := reload(args)
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf("expected error containing %q; got %v", tc.errMsg, err)
+			}
+			// none of the changes is applied
+			if conf.Load() != current {
+				t.Errorf("expected the current configuration to be kept; got %+v", conf.Load())
+			}
+		})
+	}
+}
+
+func TestDiffConfig(t *testing.T) {
+	a := defaultConfig()
+	a.Auth.Token = "old"
+	b := defaultConfig()
+	b.Auth.Token = "new"
+	b.Rate.MessagesPerSecond = 50
+	b.Queue.MessageCapacity = 10
+
+	changes := diffConfig(a, b)
+	paths := []string{}
+	for _, c := range changes {
+		paths = append(paths, c.String())
+	}
+	expected := []string{"auth.token: ****** -> ******", "rate.messages_per_second: 1000 -> 50", "queue.message_capacity: 1000 -> 10"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected changes %q; got %q", expected, paths)
+	}
+
+	keepRestartFields(a, b)
+	if b.Queue.MessageCapacity != a.Queue.MessageCapacity || b.Rate.MessagesPerSecond != 50 || b.Auth.Token != "new" {
+		t.Errorf("expected only the restart settings to be kept; got %+v", b)
+	}
+}
+
+func TestRequiresRestart(t *testing.T) {
+	tt := []struct {
+		path     string
+		expected bool
+	}{
+		{"input.format", true},
+		{"ingest.unix", true},
+		{"redact.rules", true},
+		{"digest.window", true},
+		{"batch.mode", true},
+		{"queue.message_capacity", true},
+		{"metrics.addr", true},
+		{"admin.token", true},
+		{"retry.dead_letter_capacity", true},
+		{"rate.burst", true},
+		{"retry.max_retrials", false},
+		{"rate.messages_per_second", false},
+		{"endpoint.url", false},
+		{"auth.token", false},
+		{"pipeline", false},
+		{"log.level", false},
+		{"timeout", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := requiresRestart(tc.path); got != tc.expected {
+				t.Errorf("expected %v; got %v", tc.expected, got)
+			}
+		})
+	}
+}
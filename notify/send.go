@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultSendOutput = "text"
+
+// sendOutputs print the response of the receiver as its status line followed by its body, or as a JSON object,
+// see sendResult
+var sendOutputs = []string{"text", "json"}
+
+// sendResult is the output of the send command with --output=json
+type sendResult struct {
+	GUID       string `json:"guid,omitempty"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Status     string `json:"status,omitempty"`
+	Body       string `json:"body"`
+	Attempts   int    `json:"attempts"`
+	Error      string `json:"error,omitempty"`
+}
+
+// runSendCommand sends a single message synchronously, retrying it up to retry.max_retrials times, and prints
+// the response of the receiver. The message is the argument, or the content of --file (- for stdin), decoded
+// with --input.
+func runSendCommand(args []string) int {
+	const (
+		fileFlagUsage   = "File with the body of the message, - for stdin (the argument by default)"
+		outputFlagUsage = "Format of the response printed. Valid values: text, json"
+	)
+	var file string
+	output := defaultSendOutput
+	define := func(fs *flag.FlagSet) {
+		fs.StringVar(&file, "file", file, fileFlagUsage)
+		fs.StringVar(&output, "output", output, outputFlagUsage)
+	}
+
+	var positional []string
+	c, err := parseCommandFlags(args, define, &positional)
+	if err == flag.ErrHelp {
+		return exitDelivered
+	}
+	if err == nil {
+		err = checkSendArgs(file, output, positional)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitInvalid
+	}
+
+	body, err := readSendBody(file, positional)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to read the message: %v\n", err)
+		return exitInvalid
+	}
+	n, err := newDecoder(c.Input.Format)(body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to decode the message: %v\n", err)
+		return exitInvalid
+	}
+
+	initLogger(c.logLevel())
+	config := c.notilibConfig()
+	config.Logger = nl.NewLogrusLogger(log.StandardLogger())
+	notilib, err = nl.New(c.Endpoint.URL, c.httpClient(), config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to start the client: %v\n", err)
+		return exitInvalid
+	}
+
+	res, attempts, err := sendWithRetrials(n, c.Retry.MaxRetrials)
+	if perr := printSendResult(os.Stdout, output, res, attempts, err); perr != nil {
+		fmt.Fprintf(os.Stderr, "unable to print the response: %v\n", perr)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "notify: message not delivered after %d attempts: %v\n", attempts, err)
+		return exitFailed
+	}
+	return exitDelivered
+}
+
+// checkSendArgs validates the flags of the send command and the source of the message
+func checkSendArgs(file, output string, positional []string) error {
+	if !validOption(output, sendOutputs) {
+		return fmt.Errorf("invalid output %q, valid values: %s", output, strings.Join(sendOutputs, ", "))
+	}
+	switch {
+	case len(positional) > 1:
+		return fmt.Errorf("unexpected argument: %s", positional[1])
+	case len(positional) == 1 && file != "":
+		return fmt.Errorf("the message can not be provided both as argument and with --file")
+	case len(positional) == 0 && file == "":
+		return fmt.Errorf("missing message, provide it as argument or with --file")
+	}
+	return nil
+}
+
+// readSendBody returns the message of the argument or the content of the file, without its trailing newline
+func readSendBody(file string, positional []string) ([]byte, error) {
+	if file == "" {
+		if positional[0] != "-" {
+			return []byte(positional[0]), nil
+		}
+		file = "-"
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSuffix(body, []byte("\n"))
+	return bytes.TrimSuffix(body, []byte("\r")), nil
+}
+
+// sendWithRetrials delivers the notification, trying it again up to retrials times while it fails. It returns the
+// response of the last attempt and the number of attempts.
+func sendWithRetrials(n nl.Notification, retrials int) (nl.Result, int, error) {
+	var res nl.Result
+	var err error
+	attempt := 0
+	for attempt <= retrials {
+		attempt++
+		res, err = notilib.SendMessageSync(context.Background(), n)
+		if err == nil {
+			break
+		}
+		log.Warnf("attempt %d failed: %v", attempt, err)
+	}
+	return res, attempt, err
+}
+
+// printSendResult writes the response of the receiver with the output format
+func printSendResult(w io.Writer, output string, res nl.Result, attempts int, err error) error {
+	if output == "json" {
+		r := sendResult{
+			GUID:       res.GUID,
+			StatusCode: res.StatusCode,
+			Status:     res.Status,
+			Body:       string(res.Body),
+			Attempts:   attempts,
+		}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		return json.NewEncoder(w).Encode(r)
+	}
+
+	if res.Status != "" {
+		if _, err := fmt.Fprintln(w, res.Status); err != nil {
+			return err
+		}
+	}
+	if len(res.Body) == 0 {
+		return nil
+	}
+	if _, err := w.Write(res.Body); err != nil {
+		return err
+	}
+	if !bytes.HasSuffix(res.Body, []byte("\n")) {
+		_, err := fmt.Fprintln(w)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+func TestCheckSendArgs(t *testing.T) {
+	tt := []struct {
+		name       string
+		file       string
+		output     string
+		positional []string
+		errMsg     string
+	}{
+		{"message argument", "", "text", []string{"disk full"}, ""},
+		{"stdin argument", "", "json", []string{"-"}, ""},
+		{"file", "message.txt", "text", nil, ""},
+		{"invalid output", "", "yaml", []string{"disk full"}, `invalid output "yaml", valid values: text, json`},
+		{"too many arguments", "", "text", []string{"disk", "full"}, "unexpected argument: full"},
+		{"argument and file", "message.txt", "text", []string{"disk full"}, "the message can not be provided both as argument and with --file"},
+		{"missing message", "", "text", nil, "missing message, provide it as argument or with --file"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkSendArgs(tc.file, tc.output, tc.positional)
+			if tc.errMsg == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || err.Error() != tc.errMsg) {
+				t.Fatalf("expected error %q; got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
+
+func TestReadSendBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message.txt")
+	if err := ioutil.WriteFile(path, []byte("disk full\non db1\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name       string
+		file       string
+		positional []string
+		expected   string
+	}{
+		{"argument", "", []string{"disk full\n"}, "disk full\n"},
+		{"file without the trailing newline", path, nil, "disk full\non db1"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			body, err := readSendBody(tc.file, tc.positional)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(body) != tc.expected {
+				t.Errorf("expected %q; got %q", tc.expected, body)
+			}
+		})
+	}
+
+	if _, err := readSendBody(filepath.Join(t.TempDir(), "missing.txt"), nil); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error; got %v", err)
+	}
+}
+
+func TestPrintSendResult(t *testing.T) {
+	res := nl.Result{GUID: "b97c73c3", StatusCode: 400, Status: "400 Bad Request", Body: []byte(`{"error":"invalid"}`)}
+	err := fmt.Errorf("unexpected HTTP Status: 400 Bad Request")
+
+	tt := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{"text", "text", "400 Bad Request\n{\"error\":\"invalid\"}\n"},
+		{"json", "json", `{"guid":"b97c73c3","status_code":400,"status":"400 Bad Request","body":"{\"error\":\"invalid\"}","attempts":3,"error":"unexpected HTTP Status: 400 Bad Request"}` + "\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := printSendResult(&out, tc.output, res, 3, err); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tc.expected {
+				t.Errorf("expected %q; got %q", tc.expected, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+const defaultBatchMode = "interval"
+const defaultBatchSize = 100
+
+// batchModes decide when the messages of the Stdin Channel are forwarded: interval takes up to --messages every
+// --interval, stream forwards them as they arrive, in batches of up to --batch-size
+var batchModes = []string{"interval", "stream"}
+
+// inputStream forwards the messages of the Stdin Channel in stream mode, nil in interval mode
+var inputStream *streamer
+
+// batchConfig are the settings of the stream mode
+type batchConfig struct {
+	Mode   string   `yaml:"mode" toml:"mode" json:"mode"`       // interval or stream
+	Size   int      `yaml:"size" toml:"size" json:"size"`       // the batch is forwarded once it has size messages
+	Linger duration `yaml:"linger" toml:"linger" json:"linger"` // longest wait of a message for its batch to fill, 0 forwards the waiting messages right away
+}
+
+// streamer forwards the messages of the Stdin Channel as they arrive. A batch is forwarded when it reaches the
+// size or the linger of its first message expires, whichever comes first.
+type streamer struct {
+	size    int
+	linger  time.Duration
+	forward func(batch []nl.Notification) // forwardMessages, replaced by the tests
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func newStreamer(c batchConfig) *streamer {
+	return &streamer{
+		size:    c.Size,
+		linger:  c.Linger.Duration,
+		forward: forwardMessages,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (s *streamer) run() {
+	defer close(s.done)
+
+	var batch []nl.Notification
+	var timer *time.Timer
+	var expired <-chan time.Time
+	for {
+		select {
+		case msg := <-stdinChan:
+			batch = append(batch, msg)
+			if s.linger == 0 {
+				// the messages already waiting join the batch
+				batch = receiveMessages(batch, s.size)
+			} else if len(batch) < s.size {
+				if timer == nil {
+					timer = time.NewTimer(s.linger)
+					expired = timer.C
+				}
+				continue
+			}
+		case <-expired:
+		case <-s.stop:
+			s.forward(batch)
+			return
+		}
+
+		if timer != nil {
+			timer.Stop()
+			timer, expired = nil, nil
+		}
+		s.forward(batch)
+		batch = nil
+	}
+}
+
+// close forwards the batch in progress and stops reading the Stdin Channel
+func (s *streamer) close() {
+	close(s.stop)
+	<-s.done
+}
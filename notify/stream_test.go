@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+// startTestStreamer runs a streamer over a new Stdin Channel, the batches forwarded are sent to the returned channel
+func startTestStreamer(t *testing.T, size int, linger time.Duration) (*streamer, chan<- nl.Notification, <-chan []string) {
+	t.Helper()
+	ch := make(chan nl.Notification, 100)
+	previous := stdinChan
+	stdinChan = ch
+	t.Cleanup(func() { stdinChan = previous })
+
+	batches := make(chan []string, 100)
+	s := newStreamer(batchConfig{Mode: "stream", Size: size, Linger: duration{linger}})
+	s.forward = func(batch []nl.Notification) {
+		bodies := []string{}
+		for _, n := range batch {
+			bodies = append(bodies, string(n.Body))
+		}
+		batches <- bodies
+	}
+	return s, ch, batches
+}
+
+func pushMessages(ch chan<- nl.Notification, from, to int) {
+	for i := from; i <= to; i++ {
+		ch <- nl.Notification{Body: []byte(strconv.Itoa(i))}
+	}
+}
+
+func nextBatch(t *testing.T, batches <-chan []string, expected []string) {
+	t.Helper()
+	select {
+	case batch := <-batches:
+		if !reflect.DeepEqual(batch, expected) {
+			t.Fatalf("expected batch %q; got %q", expected, batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("batch %q not forwarded", expected)
+	}
+}
+
+func noBatch(t *testing.T, batches <-chan []string, wait time.Duration) {
+	t.Helper()
+	select {
+	case batch := <-batches:
+		t.Fatalf("unexpected batch %q", batch)
+	case <-time.After(wait):
+	}
+}
+
+func TestStreamerSize(t *testing.T) {
+	s, ch, batches := startTestStreamer(t, 3, time.Hour)
+	pushMessages(ch, 1, 7)
+	go s.run()
+
+	nextBatch(t, batches, []string{"1", "2", "3"})
+	nextBatch(t, batches, []string{"4", "5", "6"})
+	noBatch(t, batches, 50*time.Millisecond)
+
+	// the batch in progress is forwarded on close
+	s.close()
+	nextBatch(t, batches, []string{"7"})
+}
+
+func TestStreamerLinger(t *testing.T) {
+	const linger = 100 * time.Millisecond
+	s, ch, batches := startTestStreamer(t, 10, linger)
+	go s.run()
+	defer s.close()
+
+	start := time.Now()
+	pushMessages(ch, 1, 2)
+	nextBatch(t, batches, []string{"1", "2"})
+	if elapsed := time.Since(start); elapsed < linger {
+		t.Errorf("batch forwarded after %v, before the linger", elapsed)
+	}
+
+	// the linger starts with the first message of the next batch
+	time.Sleep(2 * linger)
+	start = time.Now()
+	pushMessages(ch, 3, 3)
+	noBatch(t, batches, linger/2)
+	nextBatch(t, batches, []string{"3"})
+	if elapsed := time.Since(start); elapsed < linger {
+		t.Errorf("batch forwarded after %v, before the linger", elapsed)
+	}
+}
+
+func TestStreamerWithoutLinger(t *testing.T) {
+	s, ch, batches := startTestStreamer(t, 2, 0)
+	// the messages already waiting join the batch, up to its size
+	pushMessages(ch, 1, 5)
+	go s.run()
+	defer s.close()
+
+	nextBatch(t, batches, []string{"1", "2"})
+	nextBatch(t, batches, []string{"3", "4"})
+	nextBatch(t, batches, []string{"5"})
+
+	// a single message is forwarded right away
+	pushMessages(ch, 6, 6)
+	nextBatch(t, batches, []string{"6"})
+}
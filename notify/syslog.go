@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+// syslogNil is the value of the missing header fields and structured data
+const syslogNil = "-"
+
+// utf8BOM may start the MSG part, marking it as UTF-8
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// parseSyslog decodes a RFC 5424 message: the MSG part is sent as the body and the header fields are
+// attached as metadata (facility, severity, timestamp, hostname, app_name, proc_id, msg_id and structured_data),
+// the fields with the nil value "-" are omitted
+func parseSyslog(line []byte) (nl.Notification, error) {
+	p := &syslogParser{data: line}
+
+	pri, err := p.pri()
+	if err != nil {
+		return nl.Notification{}, err
+	}
+	version := p.field()
+	if version != "1" {
+		return nl.Notification{}, fmt.Errorf("unsupported syslog version %q", version)
+	}
+
+	metadata := map[string]string{
+		"facility": strconv.Itoa(pri / 8),
+		"severity": strconv.Itoa(pri % 8),
+	}
+	for _, name := range []string{"timestamp", "hostname", "app_name", "proc_id", "msg_id"} {
+		value := p.field()
+		if value == "" {
+			return nl.Notification{}, fmt.Errorf("missing %s", name)
+		}
+		if value == syslogNil {
+			continue
+		}
+		if name == "timestamp" {
+			if _, err := time.Parse(time.RFC3339Nano, value); err != nil {
+				return nl.Notification{}, fmt.Errorf("invalid timestamp %q", value)
+			}
+		}
+		metadata[name] = value
+	}
+
+	sd, err := p.structuredData()
+	if err != nil {
+		return nl.Notification{}, err
+	}
+	if sd != syslogNil {
+		metadata["structured_data"] = sd
+	}
+
+	msg := p.rest()
+	return nl.Notification{Body: bytes.TrimPrefix(msg, utf8BOM), Metadata: metadata}, nil
+}
+
+// syslogParser reads the parts of a syslog message in order
+type syslogParser struct {
+	data []byte
+	pos  int
+}
+
+// pri reads the <PRI> part, the facility and severity of the message
+func (p *syslogParser) pri() (int, error) {
+	end := bytes.IndexByte(p.data, '>')
+	if len(p.data) == 0 || p.data[0] != '<' || end < 2 || end > 4 {
+		return 0, fmt.Errorf("invalid syslog priority")
+	}
+	pri, err := strconv.Atoi(string(p.data[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, fmt.Errorf("invalid syslog priority %q", p.data[1:end])
+	}
+	p.pos = end + 1
+	return pri, nil
+}
+
+// field reads a header field ending with a space, it is empty at the end of the message
+func (p *syslogParser) field() string {
+	start := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] != ' ' {
+		p.pos++
+	}
+	value := string(p.data[start:p.pos])
+	if p.pos < len(p.data) {
+		p.pos++
+	}
+	return value
+}
+
+// structuredData reads the structured data, "-" or a list of [SD-ID PARAM="VALUE" ...] elements, as it is written
+func (p *syslogParser) structuredData() (string, error) {
+	if p.pos >= len(p.data) {
+		return "", fmt.Errorf("missing structured data")
+	}
+	if p.data[p.pos] == '-' {
+		return p.field(), nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.data) && p.data[p.pos] == '[' {
+		quoted := false
+		p.pos++
+		for ; p.pos < len(p.data); p.pos++ {
+			c := p.data[p.pos]
+			if quoted && c == '\\' {
+				p.pos++
+				continue
+			}
+			if c == '"' {
+				quoted = !quoted
+			}
+			if c == ']' && !quoted {
+				break
+			}
+		}
+		if p.pos >= len(p.data) {
+			return "", fmt.Errorf("unterminated structured data")
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("invalid structured data")
+	}
+	sd := string(p.data[start:p.pos])
+	if p.pos < len(p.data) {
+		if p.data[p.pos] != ' ' {
+			return "", fmt.Errorf("invalid structured data")
+		}
+		p.pos++
+	}
+	return sd, nil
+}
+
+// rest returns the MSG part
+func (p *syslogParser) rest() []byte {
+	return p.data[p.pos:]
+}
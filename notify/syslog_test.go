@@ -0,0 +1,56 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSyslog(t *testing.T) {
+	tt := []struct {
+		name     string
+		line     string
+		body     string
+		metadata map[string]string
+		errMsg   string
+	}{
+		{"all the fields", `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 42 ID47 [exampleSDID@32473 iut="3" eventSource="App"] An application event`,
+			"An application event", map[string]string{
+				"facility": "20", "severity": "5", "timestamp": "2003-10-11T22:14:15.003Z", "hostname": "mymachine.example.com",
+				"app_name": "evntslog", "proc_id": "42", "msg_id": "ID47", "structured_data": `[exampleSDID@32473 iut="3" eventSource="App"]`,
+			}, ""},
+		{"nil values", "<34>1 - - - - - - disk full", "disk full", map[string]string{"facility": "4", "severity": "2"}, ""},
+		{"UTF-8 BOM", "<13>1 - host app - - - \xEF\xBB\xBFdisk full", "disk full", map[string]string{"facility": "1", "severity": "5", "hostname": "host", "app_name": "app"}, ""},
+		{"without MSG", "<13>1 - - - - - -", "", map[string]string{"facility": "1", "severity": "5"}, ""},
+		{"several structured data elements", `<13>1 - - - - - [a x="1"][b y="a \"]\" b"] msg`, "msg", map[string]string{"facility": "1", "severity": "5", "structured_data": `[a x="1"][b y="a \"]\" b"]`}, ""},
+		{"missing priority", "1 - - - - - - msg", "", nil, "invalid syslog priority"},
+		{"priority out of range", "<192>1 - - - - - - msg", "", nil, `invalid syslog priority "192"`},
+		{"RFC 3164", "<13>Oct 11 22:14:15 host app: msg", "", nil, `unsupported syslog version "Oct"`},
+		{"invalid timestamp", "<13>1 yesterday - - - - - msg", "", nil, `invalid timestamp "yesterday"`},
+		{"missing header field", "<13>1 - host", "", nil, "missing app_name"},
+		{"missing structured data", "<13>1 - - - - -", "", nil, "missing structured data"},
+		{"unterminated structured data", `<13>1 - - - - - [a x="1" msg`, "", nil, "unterminated structured data"},
+		{"invalid structured data", "<13>1 - - - - - msg", "", nil, "invalid structured data"},
+		{"structured data not followed by a space", `<13>1 - - - - - [a]msg`, "", nil, "invalid structured data"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			n, err := parseSyslog([]byte(tc.line))
+			if tc.errMsg != "" {
+				if err == nil || err.Error() != tc.errMsg {
+					t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(n.Body) != tc.body {
+				t.Errorf("expected body %q; got %q", tc.body, n.Body)
+			}
+			if !reflect.DeepEqual(n.Metadata, tc.metadata) {
+				t.Errorf("expected metadata %v; got %v", tc.metadata, n.Metadata)
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultTailPoll = time.Second
+
+// commitMetadataKey is the metadata identifying the messages of the followed files, see commits
+const commitMetadataKey = "notify_commit"
+
+// inputCommits keeps the commits of the messages of the followed files until notilib accepts them
+var inputCommits = newCommits()
+
+// tailer follows the files matching the glob patterns, returning their lines as frames.
+// It handles the rotation (the path refers to a new file) and the truncation of the files, and discovers the new
+// files matching the patterns. The offsets of the lines committed are saved to the state file, so a restart
+// resumes after the last line committed.
+type tailer struct {
+	patterns  []string
+	statePath string // empty if the offsets are not persisted
+	poll      time.Duration
+	max       int // max size of a line
+
+	files   map[string]*tailedFile // followed files by path
+	lines   []tailLine             // lines read and not returned yet
+	started bool                   // the first scan has been done
+
+	mu      sync.Mutex // guards the committed offsets, the generation and closed fields of the files, and stopped
+	state   map[string]tailState
+	dirty   bool // the state has changed since it was saved
+	stopped bool
+
+	saveMu sync.Mutex // serializes the writes of the state file
+}
+
+// tailedFile is a followed file
+type tailedFile struct {
+	path       string
+	file       *os.File
+	inode      uint64
+	offset     int64  // bytes read from the file
+	partial    []byte // beginning of the last line, not complete yet, up to max+1 bytes
+	partialLen int    // length of the last line
+	generation int    // incremented on truncation, the commits of the previous lines are ignored
+	closed     bool   // the path refers to another file, the commits of its lines are ignored
+}
+
+// tailLine is a complete line with the offset of its end
+type tailLine struct {
+	file       *tailedFile
+	generation int
+	data       []byte
+	oversized  bool
+	end        int64
+}
+
+// tailState is the position of a file saved to the state file
+type tailState struct {
+	Inode  uint64 `json:"inode"`
+	Offset int64  `json:"offset"`
+}
+
+func newTailer(c inputConfig) (*tailer, error) {
+	t := &tailer{
+		patterns:  c.Tail,
+		statePath: c.TailState,
+		poll:      c.TailPoll.Duration,
+		max:       c.MaxMessageSize,
+		files:     map[string]*tailedFile{},
+		state:     map[string]tailState{},
+	}
+	if t.statePath == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(t.statePath)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the tail state: %v", err)
+	}
+	if err := json.Unmarshal(data, &t.state); err != nil {
+		return nil, fmt.Errorf("invalid tail state %s: %v", t.statePath, err)
+	}
+	return t, nil
+}
+
+// next returns the next line of the followed files, waiting for new lines. Once stopped, it blocks forever.
+func (t *tailer) next() (frame, error) {
+	for {
+		if t.isStopped() {
+			select {}
+		}
+		if len(t.lines) > 0 {
+			line := t.lines[0]
+			t.lines = t.lines[1:]
+			return frame{data: line.data, oversized: line.oversized, commit: func() { t.commit(line) }}, nil
+		}
+
+		t.scan()
+		if err := t.save(); err != nil {
+			log.Warnf("unable to save the tail state: %v", err)
+		}
+		if len(t.lines) == 0 {
+			time.Sleep(t.poll)
+		}
+	}
+}
+
+// scan discovers the files matching the patterns, detects the rotated and truncated ones and reads their new lines
+func (t *tailer) scan() {
+	matches := map[string]bool{}
+	for _, pattern := range t.patterns {
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			matches[path] = true
+		}
+	}
+
+	// the files followed, in a stable order
+	paths := make([]string, 0, len(t.files))
+	for path := range t.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		f := t.files[path]
+		info, err := os.Stat(path)
+		if err != nil || inode(info) != f.inode {
+			// rotated or removed: read the rest of the previous file, including its last line not ended, the new
+			// one is opened from the beginning
+			t.read(f)
+			if f.partialLen > 0 {
+				t.queueLine(f)
+			}
+			f.file.Close()
+			delete(t.files, path)
+			t.mu.Lock()
+			f.closed = true
+			delete(t.state, path)
+			t.dirty = true
+			t.mu.Unlock()
+			log.Infof("file %s rotated or removed", path)
+			continue
+		}
+		if info.Size() < f.offset {
+			log.Warnf("file %s truncated, reading it from the beginning", path)
+			f.file.Seek(0, io.SeekStart)
+			f.offset = 0
+			f.partial, f.partialLen = nil, 0
+			t.mu.Lock()
+			f.generation++
+			t.mu.Unlock()
+			t.setCommitted(f, 0)
+		}
+		t.read(f)
+	}
+
+	newPaths := []string{}
+	for path := range matches {
+		if _, ok := t.files[path]; !ok {
+			newPaths = append(newPaths, path)
+		}
+	}
+	sort.Strings(newPaths)
+	for _, path := range newPaths {
+		f, err := t.open(path)
+		if err != nil {
+			log.Warnf("unable to follow %s: %v", path, err)
+			continue
+		}
+		t.files[path] = f
+		t.read(f)
+	}
+	t.started = true
+}
+
+// open starts following the file at the saved offset. Without a saved offset, the files found on start are read
+// from the end, as tail -F, and those created afterwards from the beginning.
+func (t *tailer) open(path string) (*tailedFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	f := &tailedFile{path: path, file: file, inode: inode(info)}
+	t.mu.Lock()
+	saved, ok := t.state[path]
+	t.mu.Unlock()
+
+	switch {
+	case ok && saved.Inode == f.inode && saved.Offset <= info.Size():
+		f.offset = saved.Offset
+	case !ok && !t.started:
+		f.offset = info.Size()
+	}
+	if _, err := file.Seek(f.offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	t.setCommitted(f, f.offset)
+	log.Infof("following %s from offset %d", path, f.offset)
+	return f, nil
+}
+
+// read reads the new data of the file until EOF, queueing the complete lines
+func (t *tailer) read(f *tailedFile) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := f.file.Read(buf)
+		data := buf[:n]
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				f.append(data, t.max)
+				f.offset += int64(len(data))
+				break
+			}
+			f.append(data[:i], t.max)
+			f.offset += int64(i + 1)
+			data = data[i+1:]
+			t.queueLine(f)
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Warnf("unable to read %s: %v", f.path, err)
+			}
+			return
+		}
+	}
+}
+
+// queueLine queues the last line of the file, ending at the current offset
+func (t *tailer) queueLine(f *tailedFile) {
+	line := bytes.TrimSuffix(f.partial, []byte("\r"))
+	t.mu.Lock()
+	generation := f.generation
+	t.mu.Unlock()
+	t.lines = append(t.lines, tailLine{
+		file:       f,
+		generation: generation,
+		data:       line[:min(len(line), t.max)],
+		oversized:  f.partialLen > t.max,
+		end:        f.offset,
+	})
+	f.partial, f.partialLen = nil, 0
+}
+
+// append adds data to the last line, keeping up to max+1 bytes
+func (f *tailedFile) append(data []byte, max int) {
+	f.partialLen += len(data)
+	if room := max + 1 - len(f.partial); room > 0 {
+		f.partial = append(f.partial, data[:min(room, len(data))]...)
+	}
+}
+
+// commit records that the line has been queued into notilib, the next start resumes after it
+func (t *tailer) commit(line tailLine) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if line.generation != line.file.generation || line.file.closed {
+		return
+	}
+	// the digest may commit a line after a later one of the same file, the offset must not move back
+	if saved, ok := t.state[line.file.path]; ok && saved.Inode == line.file.inode && saved.Offset >= line.end {
+		return
+	}
+	t.setCommittedLocked(line.file, line.end)
+}
+
+func (t *tailer) setCommitted(f *tailedFile, offset int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.setCommittedLocked(f, offset)
+}
+
+func (t *tailer) setCommittedLocked(f *tailedFile, offset int64) {
+	t.state[f.path] = tailState{Inode: f.inode, Offset: offset}
+	t.dirty = true
+}
+
+// stop stops returning lines, the lines already returned can still be committed and saved
+func (t *tailer) stop() {
+	t.mu.Lock()
+	t.stopped = true
+	t.mu.Unlock()
+}
+
+func (t *tailer) isStopped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stopped
+}
+
+// save writes the committed offsets to the state file, replacing it atomically
+func (t *tailer) save() error {
+	if t.statePath == "" {
+		return nil
+	}
+	t.saveMu.Lock()
+	defer t.saveMu.Unlock()
+
+	t.mu.Lock()
+	if !t.dirty {
+		t.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(t.state, "", "  ")
+	t.dirty = false
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp := t.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.statePath)
+}
+
+// commits keeps the messages of the followed files waiting in the Stdin Channel or in the digest. A message is
+// committed once it is queued into notilib, or once it is not going to be sent anyway, so a restart does not skip
+// the lines not queued yet. The messages are identified by the commitMetadataKey metadata, as the acks.
+type commits struct {
+	mu      sync.Mutex
+	seq     uint64
+	pending map[string]func()
+}
+
+func newCommits() *commits {
+	return &commits{pending: map[string]func(){}}
+}
+
+// register returns a copy of the notification identified to be committed with commit
+func (c *commits) register(n nl.Notification, commit func()) nl.Notification {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	id := strconv.FormatUint(c.seq, 10)
+	c.pending[id] = commit
+
+	metadata := make(map[string]string, len(n.Metadata)+1)
+	for key, value := range n.Metadata {
+		metadata[key] = value
+	}
+	metadata[commitMetadataKey] = id
+	n.Metadata = metadata
+	return n
+}
+
+// commit commits the messages, in order
+func (c *commits) commit(messages ...nl.Notification) {
+	for _, commit := range c.take(messages) {
+		commit()
+	}
+}
+
+// forget discards the commits of the messages not queued, they are read again on restart unless a later line of
+// the same file is committed
+func (c *commits) forget(messages ...nl.Notification) {
+	c.take(messages)
+}
+
+func (c *commits) take(messages []nl.Notification) []func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var taken []func()
+	for _, n := range messages {
+		id, ok := n.Metadata[commitMetadataKey]
+		if !ok {
+			continue
+		}
+		if commit, ok := c.pending[id]; ok {
+			taken = append(taken, commit)
+			delete(c.pending, id)
+		}
+	}
+	return taken
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// inode returns 0 as the files have no inode, the rotation is detected only if the new file is smaller
+func inode(info os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+func newTestTailer(t *testing.T, dir string) *tailer {
+	t.Helper()
+	tl, err := newTailer(inputConfig{
+		Tail:           []string{filepath.Join(dir, "*.log")},
+		TailState:      filepath.Join(dir, "state.json"),
+		TailPoll:       duration{time.Millisecond},
+		MaxMessageSize: 1024,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tl
+}
+
+func writeTestFile(t *testing.T, path, content string, flag int) {
+	t.Helper()
+	f, err := os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// takeLines returns the data of the lines read, committing them
+func takeLines(tl *tailer) []string {
+	lines := []string{}
+	for _, line := range tl.lines {
+		lines = append(lines, string(line.data))
+		tl.commit(line)
+	}
+	tl.lines = nil
+	return lines
+}
+
+func readTestState(t *testing.T, dir string) map[string]tailState {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join(dir, "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := map[string]tailState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	return state
+}
+
+func checkLines(t *testing.T, expected, got []string) {
+	t.Helper()
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected lines %q; got %q", expected, got)
+	}
+}
+
+func TestTailerStart(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "old.log"), "a\nb\n", os.O_TRUNC)
+
+	tl := newTestTailer(t, dir)
+	tl.scan()
+	checkLines(t, []string{}, takeLines(tl))
+
+	// the files created afterwards are read from the beginning
+	writeTestFile(t, filepath.Join(dir, "new.log"), "c\n", os.O_TRUNC)
+	writeTestFile(t, filepath.Join(dir, "old.log"), "d\n", os.O_APPEND)
+	tl.scan()
+	checkLines(t, []string{"d", "c"}, takeLines(tl))
+}
+
+func TestTailerResume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeTestFile(t, path, "a\nb\n", os.O_TRUNC)
+
+	tl := newTestTailer(t, dir)
+	tl.scan()
+	writeTestFile(t, path, "c\nd\n", os.O_APPEND)
+	tl.scan()
+	if len(tl.lines) != 2 {
+		t.Fatalf("expected 2 lines; got %d", len(tl.lines))
+	}
+	// only the first line is queued before the restart
+	tl.commit(tl.lines[0])
+	tl.stop()
+	if err := tl.save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := readTestState(t, dir)[path].Offset; got != 6 {
+		t.Errorf("expected offset 6; got %d", got)
+	}
+
+	tl = newTestTailer(t, dir)
+	tl.scan()
+	checkLines(t, []string{"d"}, takeLines(tl))
+
+	// a file replaced while stopped is read from the beginning
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := tl.save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeTestFile(t, path+".new", "e\n", os.O_TRUNC)
+	if err := os.Rename(path+".new", path); err != nil {
+		t.Fatal(err)
+	}
+	tl = newTestTailer(t, dir)
+	tl.scan()
+	checkLines(t, []string{"e"}, takeLines(tl))
+}
+
+func TestTailerRotation(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the rotation is not detected without inodes")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeTestFile(t, path, "a\n", os.O_TRUNC)
+
+	tl := newTestTailer(t, dir)
+	tl.scan()
+	// the last line of the rotated file has no newline
+	writeTestFile(t, path, "b\nc", os.O_APPEND)
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, path, "d\n", os.O_TRUNC)
+
+	tl.scan()
+	lines := tl.lines
+	checkLines(t, []string{"b", "c", "d"}, takeLines(tl))
+
+	// the commits of the rotated file do not overwrite the offset of the new one
+	if err := tl.save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := tailState{Inode: inode(info), Offset: 2}
+	if got := readTestState(t, dir)[path]; got != expected {
+		t.Errorf("expected state %+v; got %+v", expected, got)
+	}
+	if lines[0].file != lines[1].file || lines[1].file == lines[2].file {
+		t.Errorf("expected the first two lines from the rotated file")
+	}
+}
+
+func TestTailerTruncation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeTestFile(t, path, "a\n", os.O_TRUNC)
+
+	tl := newTestTailer(t, dir)
+	tl.scan()
+	writeTestFile(t, path, "bb\n", os.O_APPEND)
+	tl.scan()
+	stale := tl.lines[0]
+	tl.lines = nil
+
+	writeTestFile(t, path, "c\n", os.O_TRUNC)
+	tl.scan()
+	checkLines(t, []string{"c"}, takeLines(tl))
+
+	// a line read before the truncation is committed late
+	tl.commit(stale)
+	if err := tl.save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := readTestState(t, dir)[path].Offset; got != 2 {
+		t.Errorf("expected offset 2; got %d", got)
+	}
+}
+
+func TestTailerCommitOrder(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	writeTestFile(t, path, "", os.O_TRUNC)
+
+	tl := newTestTailer(t, dir)
+	tl.scan()
+	writeTestFile(t, path, "a\nb\n", os.O_APPEND)
+	tl.scan()
+
+	// the digest may commit the lines out of order
+	tl.commit(tl.lines[1])
+	tl.commit(tl.lines[0])
+	if err := tl.save(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := readTestState(t, dir)[path].Offset; got != 4 {
+		t.Errorf("expected offset 4; got %d", got)
+	}
+}
+
+func TestCommits(t *testing.T) {
+	c := newCommits()
+	committed := []int{}
+	register := func(i int) nl.Notification {
+		return c.register(nl.Notification{Body: []byte("msg"), Metadata: map[string]string{"host": "db1"}}, func() {
+			committed = append(committed, i)
+		})
+	}
+	first, second, third := register(1), register(2), register(3)
+	if first.Metadata["host"] != "db1" || first.Metadata[commitMetadataKey] == second.Metadata[commitMetadataKey] {
+		t.Fatalf("unexpected metadata %v and %v", first.Metadata, second.Metadata)
+	}
+
+	c.forget(second)
+	c.commit(first, second, third, nl.Notification{Body: []byte("untracked")})
+	c.commit(first)
+	if expected := []int{1, 3}; !reflect.DeepEqual(expected, committed) {
+		t.Errorf("expected commits %v; got %v", expected, committed)
+	}
+}
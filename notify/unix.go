@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultUnixAck = "queued"
+
+// unixAcks are the responses awaited by the clients of the unix socket: queued answers with the GUID once the
+// message is queued into notilib, delivered also confirms that the receiver has accepted it
+var unixAcks = []string{"queued", "delivered"}
+
+// ackMetadataKey is the metadata identifying the messages of the unix socket clients, see acks
+const ackMetadataKey = "notify_ack"
+
+// unixClientBuffer is the number of responses waiting to be written, a client not reading them is disconnected
+const unixClientBuffer = 1000
+
+// inputAcks routes the responses to the unix socket clients
+var inputAcks = newAcks()
+
+// ackResponse is a JSON line written back to a unix socket client
+type ackResponse struct {
+	Line       int    `json:"line"`   // number of the message in the input of the client
+	Status     string `json:"status"` // queued, delivered, rejected, dropped, digested, cancelled, expired or failed
+	GUID       string `json:"guid,omitempty"`
+	Index      *int   `json:"index,omitempty"`       // index of the message in the batch of the GUID
+	StatusCode int    `json:"status_code,omitempty"` // status code of the receiver once delivered
+	Error      string `json:"error,omitempty"`
+}
+
+// serveUnix reads the messages of every client of the unix socket with the input format and framing, answering
+// every message with an ackResponse
+func (in *ingest) serveUnix(path string) error {
+	if err := removeStaleSocket(path); err != nil {
+		return fmt.Errorf("unable to listen for unix messages: %v", err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("unable to listen for unix messages: %v", err)
+	}
+	decode := newDecoder(in.input.Format)
+	in.serveConnections("unix", l, func(conn net.Conn, source string) error {
+		client := newUnixClient(conn, in.ch, source, in.ack)
+		defer client.close()
+		if err := readInput(newFramer(conn, in.input), in.input, decode, client); err != nil {
+			return err
+		}
+		// the client may close its side of the connection and keep reading the responses
+		client.wait()
+		return nil
+	})
+	return nil
+}
+
+// removeStaleSocket removes the socket left by a previous instance, it fails if the socket is in use
+func removeStaleSocket(path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and it is not a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use", path)
+	}
+	return os.Remove(path)
+}
+
+// unixClient is the inputSink of a unix socket connection
+type unixClient struct {
+	conn    net.Conn
+	ch      chan<- nl.Notification
+	name    string
+	ack     string
+	out     chan ackResponse // responses waiting to be written
+	done    chan struct{}    // closed once the responses have been written
+	pending sync.WaitGroup   // messages waiting for their last response
+}
+
+func newUnixClient(conn net.Conn, ch chan<- nl.Notification, name, ack string) *unixClient {
+	c := &unixClient{
+		conn: conn,
+		ch:   ch,
+		name: name,
+		ack:  ack,
+		out:  make(chan ackResponse, unixClientBuffer),
+		done: make(chan struct{}),
+	}
+	go c.write()
+	return c
+}
+
+func (c *unixClient) push(num int, n nl.Notification) {
+	c.pending.Add(1)
+	c.ch <- inputAcks.register(c, num, n)
+}
+
+func (c *unixClient) reject(num int, data []byte, err error) {
+	inputRejects.reject(c.name, num, data, err)
+	c.respond(ackResponse{Line: num, Status: "rejected", Error: err.Error()})
+}
+
+// respond queues a response, the client is disconnected if it does not read them
+func (c *unixClient) respond(res ackResponse) {
+	select {
+	case c.out <- res:
+	default:
+		log.Warnf("%s disconnected: too many responses not read", c.name)
+		c.conn.Close()
+	}
+}
+
+func (c *unixClient) write() {
+	defer close(c.done)
+	enc := json.NewEncoder(c.conn)
+	for res := range c.out {
+		if err := enc.Encode(res); err != nil {
+			// the remaining responses are discarded
+			c.conn.Close()
+		}
+	}
+}
+
+// wait waits for the last response of every message
+func (c *unixClient) wait() {
+	c.pending.Wait()
+}
+
+// close discards the responses not received yet and writes the rest
+func (c *unixClient) close() {
+	inputAcks.forget(c)
+	close(c.out)
+	<-c.done
+}
+
+// acks keeps the messages of the unix socket clients waiting for a response. The messages are identified by
+// the ackMetadataKey metadata, which travels with them through notilib, its retries and its reports.
+type acks struct {
+	mu      sync.Mutex
+	seq     uint64
+	pending map[string]pendingAck
+}
+
+type pendingAck struct {
+	client *unixClient
+	line   int
+}
+
+func newAcks() *acks {
+	return &acks{pending: map[string]pendingAck{}}
+}
+
+// register returns a copy of the notification identified as a message of the client
+func (a *acks) register(c *unixClient, line int, n nl.Notification) nl.Notification {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.seq++
+	id := strconv.FormatUint(a.seq, 10)
+	a.pending[id] = pendingAck{client: c, line: line}
+
+	metadata := make(map[string]string, len(n.Metadata)+1)
+	for key, value := range n.Metadata {
+		metadata[key] = value
+	}
+	metadata[ackMetadataKey] = id
+	n.Metadata = metadata
+	return n
+}
+
+// queued answers with the GUID assigned to the messages, the index of a message is its position in the batch
+func (a *acks) queued(messages []nl.Notification, guid string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, n := range messages {
+		id, p, ok := a.lookup(n)
+		if !ok {
+			continue
+		}
+		index := i
+		p.client.respond(ackResponse{Line: p.line, Status: "queued", GUID: guid, Index: &index})
+		if p.client.ack == "queued" {
+			a.resolve(id, p)
+		}
+	}
+}
+
+// delivered confirms the delivery of a message
+func (a *acks) delivered(s nl.NSuccess) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if id, p, ok := a.lookup(s.Notification); ok {
+		p.client.respond(ackResponse{Line: p.line, Status: "delivered", GUID: s.GUID, Index: &s.Index, StatusCode: s.StatusCode})
+		a.resolve(id, p)
+	}
+}
+
+// dropped reports a message dropped by the pipeline
+func (a *acks) dropped(n nl.Notification) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if id, p, ok := a.lookup(n); ok {
+		p.client.respond(ackResponse{Line: p.line, Status: "dropped"})
+		a.resolve(id, p)
+	}
+}
+
+// digested answers the messages grouped into the summary with the GUID and the index of the summary, it is their
+// last response as the summary is not confirmed to every message
+func (a *acks) digested(messages []nl.Notification, guid string, index int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, n := range messages {
+		if id, p, ok := a.lookup(n); ok {
+			p.client.respond(ackResponse{Line: p.line, Status: "digested", GUID: guid, Index: &index})
+			a.resolve(id, p)
+		}
+	}
+}
+
+// discarded reports a queued message that notilib will not deliver, as it has been cancelled or it has expired
+func (a *acks) discarded(d nl.NDiscard) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if id, p, ok := a.lookup(d.Notification); ok {
+		p.client.respond(ackResponse{Line: p.line, Status: d.Reason, GUID: d.GUID, Index: &d.Index})
+		a.resolve(id, p)
+	}
+}
+
+// failed reports a message that will not be delivered, guid is empty if it has not been queued
+func (a *acks) failed(n nl.Notification, guid string, index int, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	id, p, ok := a.lookup(n)
+	if !ok {
+		return
+	}
+	res := ackResponse{Line: p.line, Status: "failed", Error: err.Error()}
+	if guid != "" {
+		res.GUID, res.Index = guid, &index
+	}
+	p.client.respond(res)
+	a.resolve(id, p)
+}
+
+// forget discards the messages of a disconnected client
+func (a *acks) forget(c *unixClient) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for id, p := range a.pending {
+		if p.client == c {
+			a.resolve(id, p)
+		}
+	}
+}
+
+func (a *acks) lookup(n nl.Notification) (string, pendingAck, bool) {
+	id, ok := n.Metadata[ackMetadataKey]
+	if !ok {
+		return "", pendingAck{}, false
+	}
+	p, ok := a.pending[id]
+	return id, p, ok
+}
+
+func (a *acks) resolve(id string, p pendingAck) {
+	delete(a.pending, id)
+	p.client.pending.Done()
+}
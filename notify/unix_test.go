@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	nl "github.com/daniel-gil/notifications-client/notilib"
+)
+
+// startTestUnixClient connects a unix socket client to a pipe, the responses are read from the returned end. The
+// acks are routed by a new inputAcks.
+func startTestUnixClient(t *testing.T, ack string) (*unixClient, chan nl.Notification, net.Conn, *json.Decoder) {
+	t.Helper()
+	previous := inputAcks
+	inputAcks = newAcks()
+	inputRejects, _ = newRejects("")
+	t.Cleanup(func() { inputAcks = previous })
+
+	conn, peer := net.Pipe()
+	t.Cleanup(func() { peer.Close() })
+	ch := make(chan nl.Notification, 10)
+	return newUnixClient(conn, ch, "unix", ack), ch, peer, json.NewDecoder(peer)
+}
+
+func nextResponse(t *testing.T, peer net.Conn, dec *json.Decoder, expected ackResponse) {
+	t.Helper()
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	var res ackResponse
+	if err := dec.Decode(&res); err != nil {
+		t.Fatalf("expected response %+v; got error: %v", expected, err)
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("expected response %+v; got %+v", expected, res)
+	}
+}
+
+func noResponse(t *testing.T, peer net.Conn, dec *json.Decoder) {
+	t.Helper()
+	peer.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	var res ackResponse
+	if err := dec.Decode(&res); err == nil {
+		t.Errorf("unexpected response %+v", res)
+	}
+}
+
+// waitClient reports whether every message of the client has received its last response
+func waitClient(c *unixClient, wait time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		c.wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(wait):
+		return false
+	}
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestUnixAckQueued(t *testing.T) {
+	c, ch, peer, dec := startTestUnixClient(t, "queued")
+	c.push(1, nl.Notification{Body: []byte("disk full"), Metadata: map[string]string{"host": "web-1"}})
+	n := <-ch
+	if n.Metadata["host"] != "web-1" || n.Metadata[ackMetadataKey] == "" {
+		t.Fatalf("expected the message to be identified keeping its metadata; got %v", n.Metadata)
+	}
+	if waitClient(c, 10*time.Millisecond) {
+		t.Fatal("expected the message to wait for its response")
+	}
+
+	inputAcks.queued([]nl.Notification{n}, "guid-1")
+	nextResponse(t, peer, dec, ackResponse{Line: 1, Status: "queued", GUID: "guid-1", Index: intPtr(0)})
+	if !waitClient(c, time.Second) {
+		t.Fatal("expected the queued response to be the last one")
+	}
+	// the delivery is not reported to the client
+	inputAcks.delivered(nl.NSuccess{GUID: "guid-1", StatusCode: 200, Notification: n})
+	c.close()
+	noResponse(t, peer, dec)
+}
+
+func TestUnixAckDelivered(t *testing.T) {
+	c, ch, peer, dec := startTestUnixClient(t, "delivered")
+	defer c.close()
+	c.push(1, nl.Notification{Body: []byte("disk full")})
+	c.push(2, nl.Notification{Body: []byte("cpu high")})
+	first, second := <-ch, <-ch
+
+	inputAcks.queued([]nl.Notification{first, second}, "guid-1")
+	nextResponse(t, peer, dec, ackResponse{Line: 1, Status: "queued", GUID: "guid-1", Index: intPtr(0)})
+	nextResponse(t, peer, dec, ackResponse{Line: 2, Status: "queued", GUID: "guid-1", Index: intPtr(1)})
+	if waitClient(c, 10*time.Millisecond) {
+		t.Fatal("expected the messages to wait for their delivery")
+	}
+
+	inputAcks.delivered(nl.NSuccess{GUID: "guid-1", Index: 1, StatusCode: 200, Notification: second})
+	nextResponse(t, peer, dec, ackResponse{Line: 2, Status: "delivered", GUID: "guid-1", Index: intPtr(1), StatusCode: 200})
+	inputAcks.failed(first, "guid-1", 0, errors.New("status 500"))
+	nextResponse(t, peer, dec, ackResponse{Line: 1, Status: "failed", GUID: "guid-1", Index: intPtr(0), Error: "status 500"})
+	if !waitClient(c, time.Second) {
+		t.Fatal("expected every message to have received its last response")
+	}
+}
+
+func TestUnixRejected(t *testing.T) {
+	c, ch, peer, dec := startTestUnixClient(t, "queued")
+	input := inputConfig{MaxMessageSize: 1024}
+	done := make(chan error, 1)
+	go func() {
+		done <- readInput(newFramer(c.conn, input), input, decodeJSONMessage, c)
+	}()
+
+	peer.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := peer.Write([]byte("{\"body\":\"disk full\"}\nnot json\n")); err != nil {
+		t.Fatal(err)
+	}
+	nextResponse(t, peer, dec, ackResponse{Line: 2, Status: "rejected", Error: "invalid character 'o' in literal null (expecting 'u')"})
+	if n := <-ch; string(n.Body) != "disk full" {
+		t.Errorf("expected body %q; got %q", "disk full", n.Body)
+	}
+	if got := inputRejects.rejected(); got != 1 {
+		t.Errorf("expected 1 rejected message; got %d", got)
+	}
+
+	peer.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("input not finished once the client disconnected")
+	}
+	c.close()
+}
+
+func TestUnixSlowReader(t *testing.T) {
+	c, _, peer, dec := startTestUnixClient(t, "queued")
+	// the first response is being written, the client is disconnected once the buffer is full
+	for i := 0; i <= unixClientBuffer+1; i++ {
+		c.respond(ackResponse{Line: i + 1, Status: "rejected", Error: "invalid message"})
+	}
+	peer.SetReadDeadline(time.Now().Add(time.Second))
+	var res ackResponse
+	if err := dec.Decode(&res); err == nil {
+		t.Fatalf("expected the client to be disconnected; got response %+v", res)
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		c.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("pending responses not discarded")
+	}
+}
+
+func TestUnixForget(t *testing.T) {
+	c, ch, peer, dec := startTestUnixClient(t, "delivered")
+	c.push(1, nl.Notification{Body: []byte("disk full")})
+	n := <-ch
+
+	// the client disconnects before its message is queued
+	c.close()
+	if !waitClient(c, time.Second) {
+		t.Fatal("expected the messages of the client to be forgotten")
+	}
+	if len(inputAcks.pending) != 0 {
+		t.Errorf("expected no pending messages; got %v", inputAcks.pending)
+	}
+	// the responses of the forgotten messages are not routed to the client
+	inputAcks.queued([]nl.Notification{n}, "guid-1")
+	inputAcks.delivered(nl.NSuccess{GUID: "guid-1", StatusCode: 200, Notification: n})
+	noResponse(t, peer, dec)
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, "stale.sock")
+	l, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	inUse := filepath.Join(dir, "in-use.sock")
+	l, err = net.Listen("unix", inUse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	tt := []struct {
+		name    string
+		path    string
+		removed bool
+		errMsg  string
+	}{
+		{"missing", filepath.Join(dir, "missing.sock"), true, ""},
+		{"stale socket", stale, true, ""},
+		{"not a socket", file, false, file + " exists and it is not a socket"},
+		{"socket in use", inUse, false, inUse + " is in use"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := removeStaleSocket(tc.path)
+			if tc.errMsg == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.errMsg != "" && (err == nil || err.Error() != tc.errMsg) {
+				t.Fatalf("expected error message: %s; got: %v", tc.errMsg, err)
+			}
+			if _, err := os.Stat(tc.path); os.IsNotExist(err) != tc.removed {
+				t.Errorf("expected removed %v; got stat error: %v", tc.removed, err)
+			}
+		})
+	}
+}
@@ -2,18 +2,54 @@ package notilib
 
 import (
 	"fmt"
+	"net/http"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// OverflowPolicy defines the behaviour of NotifyContext when the Message Channel is full
+type OverflowPolicy int
+
+const (
+	OverflowBlock  OverflowPolicy = iota // wait until there is room in the Message Channel or the context is done
+	OverflowReject                       // fail immediately returning ErrQueueFull
+	OverflowSpill                        // write the message to a spool file on disk, only valid for PausedOverflow
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowReject:
+		return "reject"
+	case OverflowSpill:
+		return "spill"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
 // Config is the configuration for initializing the notilib. It is optional, if nil is passed, default values will be used.
 type Config struct {
-	BurstLimit           int       // Burst limit for the listener, allowing to process several messages from the Message Channel per rate
-	NumMessagesPerSecond int       // Maximal number of messages to be processed per second (it will be used to calculate the rate for the rate limiter)
-	MsgChanCap           int       // Message Channel Capacity
-	ErrChanCap           int       // Error Channel Capacity
-	LogLevel             log.Level // log level for logrus
+	BurstLimit           int                  // Burst limit for the listener, allowing to process several messages from the Message Channel per rate
+	NumMessagesPerSecond int                  // Maximal number of messages to be processed per second (it will be used to calculate the rate for the rate limiter), up to MaxNumMessagesPerSecond
+	MsgChanCap           int                  // Message Channel Capacity
+	ErrChanCap           int                  // Error Channel Capacity
+	SuccessChanCap       int                  // Success Channel Capacity, 0 disables the Success Channel
+	DiscardChanCap       int                  // Discard Channel Capacity, 0 disables the Discard Channel
+	LogLevel             log.Level            // log level of the default logger
+	Overflow             OverflowPolicy       // Behaviour of NotifyContext when the Message Channel is full
+	Method               string               // HTTP method used for sending the notifications
+	Header               http.Header          // Headers sent with every notification (e.g. Authorization), unless the notification sets them
+	Transport            Transport            // Custom transport for delivering the notifications, by default it is chosen from the URL scheme
+	Metrics              Metrics              // Receiver of the events for monitoring purposes (optional)
+	TracerProvider       trace.TracerProvider // Provider of the tracer for the enqueue, dispatch and retry spans, by default the global one
+	Logger               Logger               // Structured logger, by default a logrus logger writing to stderr with LogLevel
+	DeadLetterCap        int                  // Capacity of the dead letter queue, 0 disables it
+	PausedOverflow       OverflowPolicy       // Behaviour of Notify and NotifyContext when the Message Channel is full while paused
+	SpillDir             string               // Directory of the spool file for the OverflowSpill policy, by default the temporary directory
+	Redactor             *Redactor            // Replaces the sensitive values of the notifications before they are encoded (optional)
 }
 
 func DefaultConfig() *Config {
@@ -23,6 +59,10 @@ func DefaultConfig() *Config {
 		MsgChanCap:           defaultMsgChCap,
 		ErrChanCap:           defaultErrChCap,
 		LogLevel:             defaultLogLevel,
+		Overflow:             defaultOverflow,
+		Method:               defaultMethod,
+		DeadLetterCap:        defaultDeadLetterCap,
+		PausedOverflow:       defaultPausedOverflow,
 	}
 }
 
@@ -33,7 +73,14 @@ func (c Config) String() string {
 	sb.WriteString(fmt.Sprintf("  NumMessagesPerSecond: %d,\n", c.NumMessagesPerSecond))
 	sb.WriteString(fmt.Sprintf("  MsgChanCap: %d,\n", c.MsgChanCap))
 	sb.WriteString(fmt.Sprintf("  ErrChanCap: %d,\n", c.ErrChanCap))
+	sb.WriteString(fmt.Sprintf("  SuccessChanCap: %d,\n", c.SuccessChanCap))
+	sb.WriteString(fmt.Sprintf("  DiscardChanCap: %d,\n", c.DiscardChanCap))
 	sb.WriteString(fmt.Sprintf("  LogLevel: %v,\n", c.LogLevel))
+	sb.WriteString(fmt.Sprintf("  Overflow: %v,\n", c.Overflow))
+	sb.WriteString(fmt.Sprintf("  Method: %s,\n", c.Method))
+	sb.WriteString(fmt.Sprintf("  DeadLetterCap: %d,\n", c.DeadLetterCap))
+	sb.WriteString(fmt.Sprintf("  PausedOverflow: %v,\n", c.PausedOverflow))
+	sb.WriteString(fmt.Sprintf("  SpillDir: %s,\n", c.SpillDir))
 	sb.WriteString(fmt.Sprintf("}\n"))
 	return sb.String()
 }
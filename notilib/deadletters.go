@@ -0,0 +1,54 @@
+package notilib
+
+import "sync"
+
+// deadLetters keeps the notifications that exhausted their retrials, dropping the oldest ones when it is full
+type deadLetters struct {
+	mu       sync.Mutex
+	capacity int
+	errors   []NError
+}
+
+func newDeadLetters(capacity int) *deadLetters {
+	return &deadLetters{capacity: capacity}
+}
+
+// add stores the failed notification and reports whether the oldest one has been dropped to make room. Nothing
+// is stored nor dropped when the dead letters are disabled.
+func (d *deadLetters) add(e NError) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.capacity <= 0 {
+		return false
+	}
+	dropped := false
+	if len(d.errors) == d.capacity {
+		d.errors = d.errors[1:]
+		dropped = true
+	}
+	d.errors = append(d.errors, e)
+	return dropped
+}
+
+// list returns a copy of the stored notifications, from the oldest to the newest
+func (d *deadLetters) list() []NError {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]NError(nil), d.errors...)
+}
+
+// take returns the stored notifications and empties the queue
+func (d *deadLetters) take() []NError {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	errors := d.errors
+	d.errors = nil
+	return errors
+}
+
+func (d *deadLetters) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.errors)
+}
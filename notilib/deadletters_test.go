@@ -0,0 +1,48 @@
+package notilib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDeadLetters(t *testing.T) {
+	tt := []struct {
+		name       string
+		capacity   int
+		numAdded   int
+		expected   []int
+		numDropped int
+	}{
+		{"Positive TC", 3, 2, []int{0, 1}, 0},
+		{"Positive TC: oldest dropped when full", 3, 5, []int{2, 3, 4}, 2},
+		{"Negative TC: disabled", 0, 2, []int{}, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newDeadLetters(tc.capacity)
+			numDropped := 0
+			for i := 0; i < tc.numAdded; i++ {
+				if d.add(NError{GUID: fmt.Sprintf("guid-%d", i), Index: i}) {
+					numDropped++
+				}
+			}
+			if numDropped != tc.numDropped {
+				t.Errorf("expected %d dropped dead letters; got %d", tc.numDropped, numDropped)
+			}
+
+			if d.len() != len(tc.expected) {
+				t.Fatalf("expected %d dead letters; got %d", len(tc.expected), d.len())
+			}
+			for i, e := range d.list() {
+				if e.Index != tc.expected[i] {
+					t.Errorf("expected index %d at position %d; got %d", tc.expected[i], i, e.Index)
+				}
+			}
+
+			if taken := d.take(); len(taken) != len(tc.expected) || d.len() != 0 {
+				t.Errorf("expected the queue to be emptied; taken %d, remaining %d", len(taken), d.len())
+			}
+		})
+	}
+}
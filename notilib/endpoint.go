@@ -0,0 +1,110 @@
+package notilib
+
+import (
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"text/template"
+)
+
+// allowedMethods are the HTTP methods accepted for sending notifications
+var allowedMethods = []string{"POST", "PUT", "PATCH", "DELETE", "GET"}
+
+// endpoint is the destination of the notifications: an HTTP method and a URL template
+type endpoint struct {
+	method string
+	url    string
+	tmpl   *template.Template
+}
+
+// templateData is the data available to the URL template of the endpoint
+type templateData struct {
+	GUID          string            // GUID of the batch
+	Index         int               // Index of the message inside the batch
+	CorrelationID string            // Notification.CorrelationID
+	Metadata      map[string]string // Notification.Metadata
+}
+
+var templateFuncs = template.FuncMap{
+	"query": neturl.QueryEscape,
+	"path":  neturl.PathEscape,
+}
+
+// newEndpoint validates the method and the URL template. The URL is rendered with empty data
+// to check that it results in a valid URL.
+func newEndpoint(url, method string) (*endpoint, error) {
+	if url == "" {
+		return nil, fmt.Errorf("empty URL")
+	}
+
+	method = strings.ToUpper(method)
+	if !isAllowedMethod(method) {
+		return nil, fmt.Errorf("invalid HTTP method %q, valid values: %s", method, strings.Join(allowedMethods, ", "))
+	}
+
+	tmpl, err := template.New("url").Funcs(templateFuncs).Option("missingkey=error").Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL template: %v", err)
+	}
+
+	var sample strings.Builder
+	err = template.Must(tmpl.Clone()).Option("missingkey=zero").Execute(&sample, templateData{})
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL template: %v", err)
+	}
+	if _, err := checkURLFormat(sample.String()); err != nil {
+		return nil, err
+	}
+
+	return &endpoint{
+		method: method,
+		url:    url,
+		tmpl:   tmpl,
+	}, nil
+}
+
+// resolve renders the URL template for the delivery and applies the overrides of its notification
+func (e *endpoint) resolve(d Delivery) (string, *neturl.URL, error) {
+	n := d.Notification
+
+	var url strings.Builder
+	err := e.tmpl.Execute(&url, templateData{
+		GUID:          d.GUID,
+		Index:         d.Index,
+		CorrelationID: n.CorrelationID,
+		Metadata:      n.Metadata,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to render the URL template: %v", err)
+	}
+	target, err := neturl.ParseRequestURI(url.String())
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid URL %q: %v", url.String(), err)
+	}
+
+	if n.Path != "" {
+		ref, err := neturl.Parse(n.Path)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid path %q: %v", n.Path, err)
+		}
+		target = target.ResolveReference(ref)
+	}
+
+	method := e.method
+	if n.Method != "" {
+		method = strings.ToUpper(n.Method)
+		if !isAllowedMethod(method) {
+			return "", nil, fmt.Errorf("invalid HTTP method %q, valid values: %s", n.Method, strings.Join(allowedMethods, ", "))
+		}
+	}
+	return method, target, nil
+}
+
+func isAllowedMethod(method string) bool {
+	for _, m := range allowedMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
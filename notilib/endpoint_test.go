@@ -0,0 +1,78 @@
+package notilib
+
+import (
+	"log"
+	"testing"
+)
+
+func TestNewEndpoint(t *testing.T) {
+	tt := []struct {
+		name   string
+		url    string
+		method string
+		errMsg string
+	}{
+		{"Positive TC", "http://localhost/api", "POST", ""},
+		{"Positive TC: template", "http://localhost/resources/{{.Metadata.id | path}}?guid={{.GUID}}", "put", ""},
+		{"Missing URL", "", "POST", "empty URL"},
+		{"Invalid URL", "http/abc", "POST", "invalid URL"},
+		{"Invalid method", "http://localhost/api", "CONNECT", "invalid HTTP method \"CONNECT\", valid values: POST, PUT, PATCH, DELETE, GET"},
+		{"Invalid template", "http://localhost/{{.Metadata.id", "POST", "invalid URL template: template: url:1: unclosed action"},
+		{"Unknown template field", "http://localhost/{{.Unknown}}", "POST", "invalid URL template: template: url:1:19: executing \"url\" at <.Unknown>: can't evaluate field Unknown in type notilib.templateData"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newEndpoint(tc.url, tc.method)
+			checkError(tc.errMsg, err, t)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tt := []struct {
+		name           string
+		url            string
+		notification   Notification
+		expectedMethod string
+		expectedURL    string
+		errMsg         string
+	}{
+		{"Positive TC: plain URL", "http://localhost/api", Notification{}, "PUT", "http://localhost/api", ""},
+		{"Positive TC: metadata", "http://localhost/resources/{{.Metadata.id | path}}?q={{.Metadata.q | query}}",
+			Notification{Metadata: map[string]string{"id": "a/b", "q": "x y"}}, "PUT", "http://localhost/resources/a%2Fb?q=x+y", ""},
+		{"Positive TC: GUID and index", "http://localhost/{{.GUID}}/{{.Index}}", Notification{}, "PUT", "http://localhost/1234/3", ""},
+		{"Positive TC: overrides", "http://localhost/api/", Notification{Method: "PATCH", Path: "items"}, "PATCH", "http://localhost/api/items", ""},
+		{"Positive TC: lower case method", "http://localhost/api", Notification{Method: "delete"}, "DELETE", "http://localhost/api", ""},
+		{"Invalid method", "http://localhost/api", Notification{Method: "HEAD"}, "", "", `invalid HTTP method "HEAD", valid values: POST, PUT, PATCH, DELETE, GET`},
+		{"Missing metadata", "http://localhost/resources/{{.Metadata.id}}", Notification{}, "", "",
+			"unable to render the URL template: template: url:1:38: executing \"url\" at <.Metadata.id>: map has no entry for key \"id\""},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := newEndpoint(tc.url, "PUT")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			method, target, err := e.resolve(Delivery{Notification: tc.notification, GUID: "1234", Index: 3})
+			if !checkError(tc.errMsg, err, t) {
+				if method != tc.expectedMethod {
+					t.Errorf("expected method: %s; got: %s", tc.expectedMethod, method)
+				}
+				if target.String() != tc.expectedURL {
+					t.Errorf("expected URL: %s; got: %s", tc.expectedURL, target)
+				}
+			}
+		})
+	}
+}
+
+func mustEndpoint(url string) *endpoint {
+	e, err := newEndpoint(url, "POST")
+	if err != nil {
+		log.Fatalf("unable to create the endpoint: %v", err)
+	}
+	return e
+}
@@ -5,4 +5,4 @@ var NewListener = newListener
 var NewClientHandler = newClientHandler
 var NewSender = newSender
 
-// var NewGUID = newGUID
+var NewGUID = newGUID
@@ -3,24 +3,34 @@ package notilib
 import (
 	"context"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
-
-	log "github.com/sirupsen/logrus"
 )
 
 type Listener interface {
 	listen(ctx context.Context)
-	flush(timeout time.Duration, quit chan<- bool)
+	flush(ctx context.Context) int
+	pause()
+	resume()
+	isPaused() bool
+	setRate(rate time.Duration)
 }
 
 type requestHandler struct {
-	rate       time.Duration
 	burstLimit int
 	msgChan    chan message
 	sender     sender
+	instruments
+
+	mu      sync.Mutex
+	rate    time.Duration
+	paused  bool
+	changed chan struct{} // wakes up the listen loop when the rate or the pause state change
+	delayed int64         // number of messages waiting for their NotBefore time, out of the Message Channel
 }
 
-func newListener(r time.Duration, b int, ch chan message, s sender) (Listener, error) {
+func newListener(r time.Duration, b int, ch chan message, s sender, in instruments) (Listener, error) {
 	if ch == nil {
 		return nil, fmt.Errorf("message channel can not be nil")
 	}
@@ -28,52 +38,135 @@ func newListener(r time.Duration, b int, ch chan message, s sender) (Listener, e
 		return nil, fmt.Errorf("sender can not be nil")
 	}
 	return &requestHandler{
-		rate:       r,
-		burstLimit: b,
-		msgChan:    ch,
-		sender:     s,
+		rate:        r,
+		burstLimit:  b,
+		msgChan:     ch,
+		sender:      s,
+		instruments: in,
+		changed:     make(chan struct{}, 1),
 	}, nil
 }
 
 // listen waits for receiving new notifications from the request channel and processes them
 func (l *requestHandler) listen(ctx context.Context) {
 	// generate tickets each `rate` to avoid overwhelm the server
-	tick := time.Tick(l.rate)
+	rate, paused := l.state()
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
 
 	for {
+		// while paused the messages are kept in the Message Channel
+		msgChan := l.msgChan
+		if paused {
+			msgChan = nil
+		}
+
 		select {
-		case msg := <-l.msgChan:
+		case msg := <-msgChan:
 			// here got a new message from the Message Channel
-			<-tick
+			l.metrics.QueueLength(len(l.msgChan))
+			if l.delay(msg) {
+				continue
+			}
+			<-ticker.C
 			// here got a ticket to process the message
 			go l.sender.send(msg)
+		case <-l.changed:
+			var newRate time.Duration
+			newRate, paused = l.state()
+			if newRate != rate {
+				rate = newRate
+				ticker.Reset(rate)
+			}
 		case <-ctx.Done():
-			log.Infof("listen: %v", ctx.Err())
+			l.log.Info("listener stopped", "reason", ctx.Err())
 			return
 		}
 	}
 }
 
-func (l requestHandler) flush(timeout time.Duration, quit chan<- bool) {
-	log.Debugf("Listener: %d messages to be flushed", len(l.msgChan))
+func (l *requestHandler) state() (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate, l.paused
+}
+
+// update applies a change of the rate or the pause state and wakes up the listen loop
+func (l *requestHandler) update(f func()) {
+	l.mu.Lock()
+	f()
+	l.mu.Unlock()
+
+	select {
+	case l.changed <- struct{}{}:
+	default:
+		// the listen loop has a pending wake up
+	}
+}
+
+func (l *requestHandler) pause() {
+	l.update(func() { l.paused = true })
+}
+
+func (l *requestHandler) resume() {
+	l.update(func() { l.paused = false })
+}
+
+func (l *requestHandler) isPaused() bool {
+	_, paused := l.state()
+	return paused
+}
+
+func (l *requestHandler) setRate(rate time.Duration) {
+	l.update(func() { l.rate = rate })
+}
 
-	// programming timeout
-	t := time.After(timeout)
-	go func(quit chan<- bool) {
-		<-t
-		log.Warn("timeout occurs flushing notifications")
-		quit <- true
-		return
-	}(quit)
+// flush sends the messages remaining in the Message Channel until it is empty or ctx is done.
+// It returns the number of messages flushed.
+func (l *requestHandler) flush(ctx context.Context) int {
+	l.log.Debug("flushing the Message Channel", messagesField, len(l.msgChan))
+
+	flushed := 0
+	for {
+		select {
+		case <-ctx.Done():
+			l.log.Warn("timeout occurs flushing notifications", messagesField, len(l.msgChan))
+			return flushed
+		default:
+		}
 
-	numMessages := len(l.msgChan)
-	for i := 1; i <= numMessages; i++ {
-		log.Debugf("Listener: flushing #%d message", i)
+		select {
+		case msg := <-l.msgChan:
+			l.log.Debug("flushing message", guidField, msg.guid, indexField, msg.index)
+			l.metrics.QueueLength(len(l.msgChan))
+			if l.delay(msg) {
+				continue
+			}
+			l.sender.send(msg)
+			flushed++
+		default:
+			l.log.Info("Message Channel flushed", messagesField, flushed)
+			if delayed := atomic.LoadInt64(&l.delayed); delayed > 0 {
+				l.log.Warn("delayed messages not flushed", messagesField, delayed)
+			}
+			return flushed
+		}
+	}
+}
 
-		msg := <-l.msgChan
-		l.sender.send(msg)
+// delay schedules a message whose NotBefore time has not been reached, it is queued again into the Message Channel
+// once due. Meanwhile it does not hold a ticket nor block the flush.
+func (l *requestHandler) delay(msg message) bool {
+	wait := time.Until(msg.notification.NotBefore)
+	if wait <= 0 {
+		return false
 	}
-	log.Infof("flushed %d messages", numMessages)
-	quit <- true
-	return
+	l.log.Debug("message delayed", guidField, msg.guid, indexField, msg.index, "delay", wait)
+	atomic.AddInt64(&l.delayed, 1)
+	time.AfterFunc(wait, func() {
+		l.msgChan <- msg
+		atomic.AddInt64(&l.delayed, -1)
+		l.metrics.QueueLength(len(l.msgChan))
+	})
+	return true
 }
@@ -2,18 +2,29 @@ package notilib
 
 import (
 	"context"
+	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 type MockSender struct {
-	sendMock func(msg message)
+	sendMock    func(msg message)
+	deliverMock func(ctx context.Context, msg message) (Result, error)
 }
 
 func (m *MockSender) send(msg message) {
 	m.sendMock(msg)
 }
 
+func (m *MockSender) deliver(ctx context.Context, msg message) (Result, error) {
+	return m.deliverMock(ctx, msg)
+}
+
+func (m *MockSender) setTransport(transport Transport) {}
+
+func (m *MockSender) setHeader(header http.Header) {}
+
 func TestListen(t *testing.T) {
 	tt := []struct {
 		name            string
@@ -39,24 +50,24 @@ func TestListen(t *testing.T) {
 				channel <- msg
 			}
 
-			called := false
+			var called int32
 			var mockSender sender
 			if !tc.senderNil {
 				mockSender = &MockSender{
 					sendMock: func(msg message) {
-						called = true
+						atomic.StoreInt32(&called, 1)
 					},
 				}
 			}
 
-			listener, err := NewListener(1*time.Second, 10, channel, mockSender)
+			listener, err := NewListener(1*time.Second, 10, channel, mockSender, testInstruments)
 			if !checkError(tc.errMsg, err, t) {
 				go listener.listen(context.Background())
 
 				// give some time to call send method
 				time.Sleep(2 * time.Second)
 
-				if !called {
+				if atomic.LoadInt32(&called) == 0 {
 					t.Fatalf("did not call fulfill")
 				}
 			}
@@ -66,9 +77,118 @@ func TestListen(t *testing.T) {
 
 func getDummyMessage(content string) message {
 	return message{
-		content:     content,
-		guid:        "111-222-333-444",
-		index:       3,
-		numRetrials: 0,
+		notification: Notification{Body: []byte(content)},
+		guid:         "111-222-333-444",
+		index:        3,
+		numRetrials:  0,
+	}
+}
+
+func TestPause(t *testing.T) {
+	channel := make(chan message, 10)
+	sent := make(chan message, 10)
+	mockSender := &MockSender{
+		sendMock: func(msg message) {
+			sent <- msg
+		},
+	}
+
+	listener, err := NewListener(time.Millisecond, 10, channel, mockSender, testInstruments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	listener.pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listener.listen(ctx)
+
+	channel <- getDummyMessage("body content")
+	select {
+	case <-sent:
+		t.Fatalf("message sent while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+	if len(channel) != 1 {
+		t.Errorf("expected 1 message kept in the channel; got %d", len(channel))
+	}
+
+	// a slower rate is applied to the messages delivered after resuming
+	listener.setRate(50 * time.Millisecond)
+	listener.resume()
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatalf("message not sent after resuming")
+	}
+}
+
+func TestDelayed(t *testing.T) {
+	channel := make(chan message, 10)
+	sent := make(chan message, 10)
+	mockSender := &MockSender{
+		sendMock: func(msg message) {
+			sent <- msg
+		},
+	}
+
+	listener, err := NewListener(time.Millisecond, 10, channel, mockSender, testInstruments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go listener.listen(ctx)
+
+	// the delayed message does not hold back the next one
+	delayed := getDummyMessage("delayed")
+	delayed.notification.NotBefore = time.Now().Add(200 * time.Millisecond)
+	channel <- delayed
+	channel <- getDummyMessage("right away")
+
+	for _, expected := range []string{"right away", "delayed"} {
+		select {
+		case msg := <-sent:
+			if string(msg.notification.Body) != expected {
+				t.Fatalf("expected message %q; got %q", expected, msg.notification.Body)
+			}
+			if expected == "delayed" && time.Now().Before(delayed.notification.NotBefore) {
+				t.Errorf("message sent before %v", delayed.notification.NotBefore)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("message %q not sent", expected)
+		}
+	}
+}
+
+func TestFlushDelayed(t *testing.T) {
+	channel := make(chan message, 10)
+	sent := make(chan message, 10)
+	mockSender := &MockSender{
+		sendMock: func(msg message) {
+			sent <- msg
+		},
+	}
+
+	listener, err := NewListener(time.Millisecond, 10, channel, mockSender, testInstruments)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	delayed := getDummyMessage("delayed")
+	delayed.notification.NotBefore = time.Now().Add(time.Hour)
+	channel <- delayed
+	channel <- getDummyMessage("right away")
+
+	// the flush does not wait for the delayed message
+	start := time.Now()
+	if flushed := listener.flush(context.Background()); flushed != 1 {
+		t.Errorf("expected 1 message flushed; got %d", flushed)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("flush blocked for %v", elapsed)
+	}
+	if len(sent) != 1 || len(channel) != 0 {
+		t.Errorf("expected 1 message sent and none queued; got %d and %d", len(sent), len(channel))
 	}
 }
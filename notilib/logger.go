@@ -0,0 +1,120 @@
+package notilib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Logger is the structured logger used by notilib.
+// The keyvals are pairs of field names and values, e.g. logger.Info("message sent", "guid", guid, "index", 3).
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Warn(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+}
+
+// Names of the fields logged by notilib
+const (
+	guidField     = "guid"
+	indexField    = "index"
+	attemptField  = "attempt"
+	statusField   = "status"
+	errorField    = "error"
+	messagesField = "messages"
+)
+
+// levelSetter is implemented by the loggers whose level can be changed at runtime
+type levelSetter interface {
+	setLevel(level logrus.Level) error
+}
+
+type logrusLogger struct {
+	logger logrus.FieldLogger
+}
+
+// NewLogrusLogger adapts a logrus logger (or entry) to the Logger interface
+func NewLogrusLogger(l logrus.FieldLogger) Logger {
+	return &logrusLogger{logger: l}
+}
+
+func (l *logrusLogger) Debug(msg string, keyvals ...interface{}) {
+	l.logger.WithFields(logrusFields(keyvals)).Debug(msg)
+}
+
+func (l *logrusLogger) Info(msg string, keyvals ...interface{}) {
+	l.logger.WithFields(logrusFields(keyvals)).Info(msg)
+}
+
+func (l *logrusLogger) Warn(msg string, keyvals ...interface{}) {
+	l.logger.WithFields(logrusFields(keyvals)).Warn(msg)
+}
+
+func (l *logrusLogger) Error(msg string, keyvals ...interface{}) {
+	l.logger.WithFields(logrusFields(keyvals)).Error(msg)
+}
+
+func (l *logrusLogger) setLevel(level logrus.Level) error {
+	switch logger := l.logger.(type) {
+	case *logrus.Logger:
+		logger.SetLevel(level)
+	case *logrus.Entry:
+		logger.Logger.SetLevel(level)
+	default:
+		return fmt.Errorf("unable to change the level of %T", l.logger)
+	}
+	return nil
+}
+
+// logrusFields converts the pairs of field names and values, a field without value is logged as "!MISSING"
+func logrusFields(keyvals []interface{}) logrus.Fields {
+	fields := make(logrus.Fields, len(keyvals)/2)
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "!MISSING"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+		fields[fmt.Sprint(keyvals[i])] = value
+	}
+	return fields
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger adapts a log/slog logger to the Logger interface
+func NewSlogLogger(l *slog.Logger) Logger {
+	return &slogLogger{logger: l}
+}
+
+func (l *slogLogger) Debug(msg string, keyvals ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelDebug, msg, keyvals...)
+}
+
+func (l *slogLogger) Info(msg string, keyvals ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelInfo, msg, keyvals...)
+}
+
+func (l *slogLogger) Warn(msg string, keyvals ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelWarn, msg, keyvals...)
+}
+
+func (l *slogLogger) Error(msg string, keyvals ...interface{}) {
+	l.logger.Log(context.Background(), slog.LevelError, msg, keyvals...)
+}
+
+// newDefaultLogger creates a private logrus logger, so the global logrus configuration of the application is not modified
+func newDefaultLogger(out io.Writer, level logrus.Level) Logger {
+	logger := logrus.New()
+	logger.SetOutput(out)
+	logger.SetFormatter(&logrus.TextFormatter{
+		FullTimestamp: true,
+	})
+	logger.SetLevel(level)
+	return NewLogrusLogger(logger)
+}
@@ -0,0 +1,98 @@
+package notilib
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+var testInstruments = instruments{
+	log:     newDefaultLogger(ioutil.Discard, logrus.DebugLevel),
+	metrics: noopMetrics{},
+	tracer:  noopTracer,
+	stats:   newStatsRecorder(noopMetrics{}),
+}
+
+func TestLoggerAdapters(t *testing.T) {
+	tt := []struct {
+		name      string
+		newLogger func(out *bytes.Buffer) Logger
+		expected  []string
+	}{
+		{"logrus", func(out *bytes.Buffer) Logger {
+			l := logrus.New()
+			l.SetOutput(out)
+			l.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+			return NewLogrusLogger(l)
+		}, []string{`level=warning`, `msg="retrial queued"`, `guid=1234`, `index=3`, `attempt="!MISSING"`}},
+		{"slog", func(out *bytes.Buffer) Logger {
+			return NewSlogLogger(slog.New(slog.NewTextHandler(out, nil)))
+		}, []string{`level=WARN`, `msg="retrial queued"`, `guid=1234`, `index=3`, `!BADKEY=attempt`}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := tc.newLogger(&out)
+
+			logger.Debug("not logged with the default level", guidField, "1234")
+			logger.Warn("retrial queued", guidField, "1234", indexField, 3, attemptField)
+
+			line := out.String()
+			if strings.Count(line, "\n") != 1 {
+				t.Errorf("expected a single line; got: %s", line)
+			}
+			for _, field := range tc.expected {
+				if !strings.Contains(line, field) {
+					t.Errorf("expected %s in: %s", field, line)
+				}
+			}
+		})
+	}
+}
+
+func TestNewWithoutGlobalSideEffects(t *testing.T) {
+	level := logrus.GetLevel()
+	formatter := logrus.StandardLogger().Formatter
+
+	conf := DefaultConfig()
+	conf.LogLevel = logrus.TraceLevel
+	if _, err := New("http://localhost/api", nil, conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if logrus.GetLevel() != level {
+		t.Errorf("global logrus level modified: expected %v; got %v", level, logrus.GetLevel())
+	}
+	if logrus.StandardLogger().Formatter != formatter {
+		t.Errorf("global logrus formatter modified")
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	tt := []struct {
+		name   string
+		logger Logger
+		errMsg string
+	}{
+		{"Positive TC: logrus logger", NewLogrusLogger(logrus.New()), ""},
+		{"Positive TC: logrus entry", NewLogrusLogger(logrus.NewEntry(logrus.New())), ""},
+		{"Negative TC: slog logger", NewSlogLogger(slog.Default()), "the logger does not support changing the level"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := DefaultConfig()
+			conf.Logger = tc.logger
+			notilib, err := New("http://localhost/api", nil, conf)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkError(tc.errMsg, notilib.SetLogLevel(logrus.WarnLevel), t)
+		})
+	}
+}
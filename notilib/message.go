@@ -1,8 +1,71 @@
 package notilib
 
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Notification is a message to be delivered to the receiver
+type Notification struct {
+	Body          []byte            // Payload sent as request body
+	ContentType   string            // Value of the Content-Type header, not set if empty
+	Header        http.Header       // Additional headers sent with the request
+	Metadata      map[string]string // Arbitrary labels attached to the notification, they are not sent to the receiver
+	CorrelationID string            // Identifier sent in the X-Correlation-ID header, not set if empty
+	Method        string            // Overrides the HTTP method used for this notification (POST by default), case-insensitive
+	Path          string            // Overrides the target, resolved against the URL passed to notilib.New
+	Priority      int               // Priority sent in the X-Priority header, not set if 0
+	Key           string            // Key sent in the X-Message-Key header, e.g. for ordering or deduplication, not set if empty
+	ExpiresAt     time.Time         // A queued notification not delivered before this time is discarded, zero means no expiration
+	NotBefore     time.Time         // A queued notification is not delivered before this time, zero means right away
+}
+
+// Headers used for sending the fields of a Notification
+const (
+	correlationIDHeader = "X-Correlation-ID"
+	priorityHeader      = "X-Priority"
+	keyHeader           = "X-Message-Key"
+)
+
 type message struct {
-	content     string // notification text message
-	guid        string // GUID: Unique identifier
-	index       int    // Index of the message from the []string passed as parameter to the notilib.Notify method
-	numRetrials int    // Current number of retrials for this notification
+	notification Notification      // notification to be delivered
+	guid         string            // GUID: Unique identifier
+	index        int               // Index of the message from the slice passed as parameter to the notilib.Notify method
+	numRetrials  int               // Current number of retrials for this notification
+	spanCtx      trace.SpanContext // Span of the enqueue operation, parent of the dispatch spans
+}
+
+// textNotifications converts plain text messages into notifications
+func textNotifications(messages []string) []Notification {
+	notifications := make([]Notification, len(messages))
+	for idx, msg := range messages {
+		notifications[idx] = Notification{Body: []byte(msg)}
+	}
+	return notifications
+}
+
+// expired reports whether the notification can not be delivered anymore
+func (n Notification) expired(now time.Time) bool {
+	return !n.ExpiresAt.IsZero() && now.After(n.ExpiresAt)
+}
+
+// withDefaultHeader returns a copy of the notification carrying the headers of h that it does not set itself
+func (n Notification) withDefaultHeader(h http.Header) Notification {
+	if len(h) == 0 {
+		return n
+	}
+	header := n.Header.Clone()
+	if header == nil {
+		header = make(http.Header, len(h))
+	}
+	for key, values := range h {
+		key = http.CanonicalHeaderKey(key)
+		if _, ok := header[key]; !ok {
+			header[key] = append([]string(nil), values...)
+		}
+	}
+	n.Header = header
+	return n
 }
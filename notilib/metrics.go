@@ -0,0 +1,62 @@
+package notilib
+
+import (
+	"errors"
+	"time"
+)
+
+// FailureClass classifies the failed deliveries
+type FailureClass string
+
+const (
+	FailureInvalid     FailureClass = "invalid"      // the notification could not be encoded for the transport
+	FailureNetwork     FailureClass = "network"      // the receiver could not be reached
+	FailureClientError FailureClass = "client_error" // the receiver answered with an HTTP 4xx status
+	FailureServerError FailureClass = "server_error" // the receiver answered with an HTTP 5xx status
+	FailureStatus      FailureClass = "status"       // the receiver answered with another unexpected status (e.g. a gRPC code)
+)
+
+// Metrics receives the events produced by notilib, allowing to expose them to a monitoring system.
+// The methods are called concurrently from several goroutines.
+type Metrics interface {
+	Enqueued(n int)                                   // n messages were queued into the Message Channel
+	Retried()                                         // a failed message was queued again into the Message Channel
+	QueueLength(n int)                                // number of messages waiting in the Message Channel
+	InFlight(n int)                                   // number of deliveries waiting for the receiver
+	Sent(latency time.Duration)                       // a message was accepted by the receiver
+	Failed(class FailureClass, latency time.Duration) // a delivery failed
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) Enqueued(n int)                                   {}
+func (noopMetrics) Retried()                                         {}
+func (noopMetrics) QueueLength(n int)                                {}
+func (noopMetrics) InFlight(n int)                                   {}
+func (noopMetrics) Sent(latency time.Duration)                       {}
+func (noopMetrics) Failed(class FailureClass, latency time.Duration) {}
+
+// invalidRequestError is returned by the transports when the notification can not be encoded
+type invalidRequestError struct {
+	error
+}
+
+func (e invalidRequestError) Unwrap() error {
+	return e.error
+}
+
+// classifyFailure derives the class of a failed delivery from its result and error
+func classifyFailure(res Result, err error) FailureClass {
+	var invalid invalidRequestError
+	switch {
+	case errors.As(err, &invalid):
+		return FailureInvalid
+	case res.StatusCode >= 400 && res.StatusCode < 500:
+		return FailureClientError
+	case res.StatusCode >= 500 && res.StatusCode < 600:
+		return FailureServerError
+	case res.Status != "":
+		return FailureStatus
+	}
+	return FailureNetwork
+}
@@ -0,0 +1,82 @@
+package notilib
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type MockMetrics struct {
+	noopMetrics
+	mu     sync.Mutex
+	sent   int
+	failed map[FailureClass]int
+}
+
+func (m *MockMetrics) Sent(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent++
+}
+
+func (m *MockMetrics) Failed(class FailureClass, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.failed == nil {
+		m.failed = make(map[FailureClass]int)
+	}
+	m.failed[class]++
+}
+
+func TestClassifyFailure(t *testing.T) {
+	tt := []struct {
+		name     string
+		res      Result
+		err      error
+		expected FailureClass
+	}{
+		{"Invalid request", Result{}, invalidRequestError{errors.New("bad template")}, FailureInvalid},
+		{"Wrapped invalid request", Result{}, fmt.Errorf("wrapped: %w", invalidRequestError{errors.New("bad template")}), FailureInvalid},
+		{"Network error", Result{}, errors.New("connection refused"), FailureNetwork},
+		{"HTTP 4xx", Result{StatusCode: http.StatusBadRequest, Status: "400 Bad Request"}, errors.New("unexpected"), FailureClientError},
+		{"HTTP 5xx", Result{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}, errors.New("unexpected"), FailureServerError},
+		{"gRPC code", Result{StatusCode: 14, Status: "Unavailable"}, errors.New("unexpected"), FailureStatus},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			class := classifyFailure(tc.res, tc.err)
+			if class != tc.expected {
+				t.Errorf("expected class: %s; got: %s", tc.expected, class)
+			}
+		})
+	}
+}
+
+func TestDeliverMetrics(t *testing.T) {
+	statusCode := http.StatusOK
+	mockDispatcher := &MockDispatcher{
+		dispatchMock: func(req *http.Request) (*http.Response, error) {
+			res := createHTTPResponse(req, "")
+			res.StatusCode = statusCode
+			res.Status = http.StatusText(statusCode)
+			return res, nil
+		},
+	}
+	metrics := &MockMetrics{}
+	sender := NewSender(newHTTPTransport(mustEndpoint("http://localhost"), mockDispatcher), nil, nil, make(chan NError, 1), nil, newTracker(), instruments{log: testInstruments.log, metrics: metrics, tracer: noopTracer, stats: testInstruments.stats})
+
+	sender.send(getDummyMessage("body content"))
+	statusCode = http.StatusServiceUnavailable
+	sender.send(getDummyMessage("body content"))
+
+	if metrics.sent != 1 {
+		t.Errorf("expected sent messages: 1; got: %d", metrics.sent)
+	}
+	if metrics.failed[FailureServerError] != 1 {
+		t.Errorf("expected server errors: 1; got: %d", metrics.failed[FailureServerError])
+	}
+}
@@ -0,0 +1,15 @@
+package notilib
+
+// Reasons of the discarded notifications
+const (
+	DiscardCancelled = "cancelled" // cancelled with Notilib.Cancel
+	DiscardExpired   = "expired"   // Notification.ExpiresAt passed before the delivery
+)
+
+// NDiscard struct sent to the Discard Channel once a queued notification will not be delivered nor reported as an error
+type NDiscard struct {
+	GUID         string       // GUID: Unique identifier
+	Index        int          // Index of the message from the slice passed as parameter to the notilib.Notify method
+	Reason       string       // DiscardCancelled or DiscardExpired
+	Notification Notification // Discarded notification
+}
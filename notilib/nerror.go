@@ -4,11 +4,12 @@ import "fmt"
 
 // NError struct sent to the Error Channel
 type NError struct {
-	ErrorMessage string // Error message
-	Content      string // Original failed notification
-	NumRetrials  int    // Number of retrials
-	GUID         string // GUID: Unique identifier
-	Index        int    // Index of the message from the []string passed as parameter to the notilib.Notify method
+	ErrorMessage string       // Error message
+	Content      string       // Body of the failed notification, redacted as it was sent (see Config.Redactor)
+	Notification Notification // Original failed notification, not redacted, to be retried
+	NumRetrials  int          // Number of retrials
+	GUID         string       // GUID: Unique identifier
+	Index        int          // Index of the message from the slice passed as parameter to the notilib.Notify method
 }
 
 // implementing the error interface
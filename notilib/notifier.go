@@ -1,57 +1,282 @@
 package notilib
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
 	uuid "github.com/satori/go.uuid"
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrQueueFull is returned when the Message Channel is full and the overflow policy is OverflowReject
+var ErrQueueFull = errors.New("message channel is full")
+
+// QueueError is returned by NotifyContext and NotifyMessages when a message can not be queued. The messages with
+// content before it, Queued of them, remain queued, the rest are discarded.
+type QueueError struct {
+	Index  int // index of the first message not queued
+	Queued int // number of messages queued
+	Err    error
+}
+
+func (e *QueueError) Error() string {
+	return fmt.Sprintf("unable to queue message[%d]: %v", e.Index, e.Err)
+}
+
+func (e *QueueError) Unwrap() error {
+	return e.Err
+}
+
 type Notifier interface {
-	notify(messages []string) (string, error)
+	notify(notifications []Notification) (string, error)
+	notifyContext(ctx context.Context, notifications []Notification) (string, error)
+	pause()
+	resume()
+	unspill() int
+	spilled() int
 }
 
 type notifier struct {
-	msgCh chan message
+	msgCh          chan message
+	tracker        tracker
+	overflow       OverflowPolicy
+	pausedOverflow OverflowPolicy // policy applied while the delivery is paused
+	spool          *spool         // messages spilled while paused, nil if spilling is not enabled
+	paused         int32
+	draining       int32
+	instruments
 }
 
-func newNotifier(msgChan chan message) (Notifier, error) {
+func newNotifier(msgChan chan message, t tracker, overflow, pausedOverflow OverflowPolicy, sp *spool, in instruments) (Notifier, error) {
 	if msgChan == nil {
 		return nil, fmt.Errorf("msgChan can not be nil")
 	}
+	if t == nil {
+		return nil, fmt.Errorf("tracker can not be nil")
+	}
+	if pausedOverflow == OverflowSpill && sp == nil {
+		return nil, fmt.Errorf("spool can not be nil with the spill policy")
+	}
 	return &notifier{
-		msgCh: msgChan,
+		msgCh:          msgChan,
+		tracker:        t,
+		overflow:       overflow,
+		pausedOverflow: pausedOverflow,
+		spool:          sp,
+		instruments:    in,
 	}, nil
 }
 
-func (n *notifier) notify(messages []string) (string, error) {
-	log.Debugf("queuing new messages: %s", messages)
-	guid, err := n.newGUID()
+func (n *notifier) notify(notifications []Notification) (string, error) {
+	guid, indexes, span, err := n.register(context.Background(), notifications)
 	if err != nil {
 		return "", err
 	}
 
 	// queueing messages into the channel to be later dispatched
-	go func(guid string, messages []string) {
-		for idx, msg := range messages {
-			// just queue those messages with content
-			if len(msg) > 0 {
-				n.msgCh <- message{
-					content:     msg,
-					guid:        guid,
-					index:       idx,
-					numRetrials: 0,
-				}
+	go func(guid string, notifications []Notification) {
+		defer span.End()
+		for pos, idx := range indexes {
+			err := n.enqueue(context.Background(), newMessage(notifications[idx], guid, idx, span.SpanContext()), n.policy(OverflowBlock))
+			if err != nil {
+				n.tracker.discard(guid, indexes[pos:])
+				n.log.Warn("messages discarded", guidField, guid, messagesField, len(indexes)-pos, errorField, err)
+				return
 			}
-			log.Debugf("message[%d] added, content=%s", idx, msg)
 		}
-		log.Debugf("%d messages inserted into the msgCh", len(messages))
-	}(guid, messages)
+		n.log.Debug("messages inserted into the Message Channel", guidField, guid, messagesField, len(indexes))
+	}(guid, notifications)
 
 	return guid, nil
 }
 
-func (n *notifier) newGUID() (string, error) {
+// notifyContext queues the messages before returning, giving up when the context is done
+// or, under the OverflowReject policy, as soon as the Message Channel is full.
+// The messages not queued are discarded, the GUID is returned anyway to allow cancelling the queued ones.
+func (n *notifier) notifyContext(ctx context.Context, notifications []Notification) (_ string, err error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	guid, indexes, span, err := n.register(ctx, notifications)
+	if err != nil {
+		return "", err
+	}
+	defer func() { endSpan(span, err) }()
+
+	for pos, idx := range indexes {
+		err := n.enqueue(ctx, newMessage(notifications[idx], guid, idx, span.SpanContext()), n.policy(n.overflow))
+		if err != nil {
+			n.tracker.discard(guid, indexes[pos:])
+			return guid, &QueueError{Index: idx, Queued: pos, Err: err}
+		}
+	}
+	n.log.Debug("messages inserted into the Message Channel", guidField, guid, messagesField, len(indexes))
+
+	return guid, nil
+}
+
+// policy returns the overflow policy to apply, the paused one while the delivery is paused
+func (n *notifier) policy(policy OverflowPolicy) OverflowPolicy {
+	if atomic.LoadInt32(&n.paused) == 1 {
+		return n.pausedOverflow
+	}
+	return policy
+}
+
+// enqueue inserts the message into the Message Channel, applying the policy when it is full
+func (n *notifier) enqueue(ctx context.Context, msg message, policy OverflowPolicy) error {
+	// keep the order while there are spilled messages waiting
+	if n.spool != nil && n.spool.len() > 0 {
+		return n.spill(msg)
+	}
+
+	switch policy {
+	case OverflowReject:
+		select {
+		case n.msgCh <- msg:
+			n.queued(msg.guid, msg.index)
+			return nil
+		default:
+			return ErrQueueFull
+		}
+	case OverflowSpill:
+		select {
+		case n.msgCh <- msg:
+			n.queued(msg.guid, msg.index)
+			return nil
+		default:
+			return n.spill(msg)
+		}
+	}
+
+	select {
+	case n.msgCh <- msg:
+		n.queued(msg.guid, msg.index)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// spill writes the message into the spool, it will be queued once the delivery is resumed
+func (n *notifier) spill(msg message) error {
+	if err := n.spool.push(msg); err != nil {
+		return err
+	}
+	n.log.Debug("message spilled", guidField, msg.guid, indexField, msg.index)
+	n.metrics.Enqueued(1)
+	return nil
+}
+
+func (n *notifier) pause() {
+	atomic.StoreInt32(&n.paused, 1)
+}
+
+// resume moves the spilled messages back into the Message Channel, as the listener makes room
+func (n *notifier) resume() {
+	atomic.StoreInt32(&n.paused, 0)
+	if n.spool == nil || !atomic.CompareAndSwapInt32(&n.draining, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&n.draining, 0)
+		for atomic.LoadInt32(&n.paused) == 0 {
+			msg, ok, err := n.spool.pop()
+			if err != nil {
+				n.log.Error("unable to read the spilled messages", errorField, err)
+				return
+			}
+			if !ok {
+				return
+			}
+			msg.spanCtx = n.tracker.spanContext(msg.guid)
+			n.msgCh <- msg
+			n.metrics.QueueLength(len(n.msgCh))
+		}
+	}()
+}
+
+// unspill moves the spilled messages into the Message Channel while there is room, without blocking.
+// It returns the number of messages moved.
+func (n *notifier) unspill() int {
+	if n.spool == nil {
+		return 0
+	}
+	moved := 0
+	for len(n.msgCh) < cap(n.msgCh) {
+		msg, ok, err := n.spool.pop()
+		if err != nil {
+			n.log.Error("unable to read the spilled messages", errorField, err)
+			return moved
+		}
+		if !ok {
+			return moved
+		}
+		msg.spanCtx = n.tracker.spanContext(msg.guid)
+		n.msgCh <- msg
+		moved++
+	}
+	return moved
+}
+
+// spilled returns the number of messages waiting in the spool
+func (n *notifier) spilled() int {
+	if n.spool == nil {
+		return 0
+	}
+	return n.spool.len()
+}
+
+// queued reports a message inserted into the Message Channel
+func (n *notifier) queued(guid string, index int) {
+	n.log.Debug("message added", guidField, guid, indexField, index)
+	n.metrics.Enqueued(1)
+	n.metrics.QueueLength(len(n.msgCh))
+}
+
+// register assigns a GUID to the messages and starts tracking those with content.
+// It starts the enqueue span, parent of the dispatch spans of the messages, that the caller has to end.
+func (n *notifier) register(ctx context.Context, notifications []Notification) (string, []int, trace.Span, error) {
+	guid, err := newGUID()
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	// just queue those messages with content
+	indexes := []int{}
+	for idx, notification := range notifications {
+		if len(notification.Body) > 0 {
+			indexes = append(indexes, idx)
+		}
+	}
+
+	_, span := n.tracer.Start(ctx, enqueueSpanName, trace.WithAttributes(
+		guidAttribute.String(guid),
+		numMessagesAttribute.Int(len(indexes)),
+	))
+
+	n.log.Debug("queuing new messages", guidField, guid, messagesField, len(indexes))
+
+	// register the batch before queueing, so it can be cancelled as soon as the GUID is returned
+	n.tracker.register(guid, indexes, span.SpanContext())
+	return guid, indexes, span, nil
+}
+
+func newMessage(n Notification, guid string, index int, sc trace.SpanContext) message {
+	return message{
+		notification: n,
+		guid:         guid,
+		index:        index,
+		numRetrials:  0,
+		spanCtx:      sc,
+	}
+}
+
+func newGUID() (string, error) {
 	guid, err := uuid.NewV4()
 	if err != nil {
 		return "", fmt.Errorf("unable to create an GUID: %v", err)
@@ -1,6 +1,8 @@
 package notilib
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 )
@@ -18,11 +20,11 @@ func TestNotify(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			notifier, err := newNotifier(tc.msgChan)
+			notifier, err := newNotifier(tc.msgChan, newTracker(), OverflowBlock, OverflowBlock, nil, testInstruments)
 
 			if tc.msgChan != nil {
 				if !checkError(tc.errMsg, err, t) {
-					notifier.notify(tc.messages)
+					notifier.notify(textNotifications(tc.messages))
 
 					// give some time to call send method
 					time.Sleep(1 * time.Second)
@@ -39,12 +41,111 @@ func TestNotify(t *testing.T) {
 	}
 }
 
+func TestNotifyContext(t *testing.T) {
+	tt := []struct {
+		name        string
+		msgChanCap  int
+		overflow    OverflowPolicy
+		timeout     time.Duration
+		messages    []string
+		numQueued   int
+		expectedErr error
+	}{
+		{"Positive TC", 20, OverflowBlock, time.Second, []string{"abc", "", "hello world"}, 2, nil},
+		{"Negative TC: queue full with reject policy", 1, OverflowReject, time.Second, []string{"abc", "zzzz"}, 1, ErrQueueFull},
+		{"Negative TC: deadline exceeded with block policy", 1, OverflowBlock, 100 * time.Millisecond, []string{"abc", "zzzz"}, 1, context.DeadlineExceeded},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			msgChan := make(chan message, tc.msgChanCap)
+			notifier, err := newNotifier(msgChan, newTracker(), tc.overflow, OverflowBlock, nil, testInstruments)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+
+			guid, err := notifier.notifyContext(ctx, textNotifications(tc.messages))
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error: %v; got: %v", tc.expectedErr, err)
+			}
+			if guid == "" {
+				t.Errorf("empty GUID")
+			}
+			if len(msgChan) != tc.numQueued {
+				t.Errorf("unexpected number of elements in msg chan: expected %d; got: %d", tc.numQueued, len(msgChan))
+			}
+			var qerr *QueueError
+			if errors.As(err, &qerr) && qerr.Queued != tc.numQueued {
+				t.Errorf("unexpected number of messages queued in the error: expected %d; got: %d", tc.numQueued, qerr.Queued)
+			}
+			if tc.expectedErr != nil && qerr == nil {
+				t.Errorf("expected a QueueError; got: %v", err)
+			}
+		})
+	}
+}
+
+func TestNotifyPaused(t *testing.T) {
+	tt := []struct {
+		name           string
+		pausedOverflow OverflowPolicy
+		expectedErr    error
+		numSpilled     int
+	}{
+		{"Positive TC: spill policy", OverflowSpill, nil, 2},
+		{"Negative TC: reject policy", OverflowReject, ErrQueueFull, 0},
+		{"Negative TC: block policy", OverflowBlock, context.DeadlineExceeded, 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			msgChan := make(chan message, 1)
+			notifier, err := newNotifier(msgChan, newTracker(), OverflowReject, tc.pausedOverflow, newSpool(t.TempDir()), testInstruments)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			notifier.pause()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			_, err = notifier.notifyContext(ctx, textNotifications([]string{"abc", "zzzz", "hello world"}))
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("expected error: %v; got: %v", tc.expectedErr, err)
+			}
+			if notifier.spilled() != tc.numSpilled {
+				t.Fatalf("expected %d spilled messages; got: %d", tc.numSpilled, notifier.spilled())
+			}
+			if tc.numSpilled == 0 {
+				return
+			}
+
+			// once resumed, the spilled messages follow the queued one in order
+			notifier.resume()
+			expected := []string{"abc", "zzzz", "hello world"}
+			for _, body := range expected {
+				select {
+				case msg := <-msgChan:
+					if string(msg.notification.Body) != body {
+						t.Errorf("expected message %s; got: %s", body, msg.notification.Body)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("message %s not queued after resuming", body)
+				}
+			}
+		})
+	}
+}
+
 func checkMessageChannelContent(messages []string, ch chan message) bool {
 	chLen := len(ch)
 	for i := 0; i < chLen; i++ {
 		msg := <-ch
 
-		idx := containsInSlice(msg.content, messages)
+		idx := containsInSlice(string(msg.notification.Body), messages)
 		if idx == -1 {
 			// not found
 			return false
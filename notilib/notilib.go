@@ -2,12 +2,18 @@ package notilib
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	neturl "net/url"
+	"os"
+	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const defaultMsgChCap = 1000
@@ -15,158 +21,627 @@ const defaultErrChCap = 500
 const defaultBurstLimit = 1000
 const defaultNumMessagesPerSecond = 1000
 const defaultLogLevel = log.InfoLevel
+const defaultOverflow = OverflowBlock
+const defaultMethod = "POST"
+const defaultDeadLetterCap = 1000
+const defaultPausedOverflow = OverflowBlock
+
+// MaxNumMessagesPerSecond is the highest rate, one message per nanosecond
+const MaxNumMessagesPerSecond = int(time.Second)
+
+var (
+	// ErrAlreadyListening is returned by Listen when the service has already been started
+	ErrAlreadyListening = errors.New("notilib is already listening")
+
+	// ErrClosed is returned when the instance is terminating or terminated and does not accept new operations
+	ErrClosed = errors.New("the application is terminating, it does not accept new notifications")
+)
 
 // Notilib interface exposes the public methods of the library
 type Notilib interface {
-	// Listen start the service that reads from the Message Channel and send them to the URL
-	Listen(ctx context.Context)
+	// Listen start the service that reads from the Message Channel and send them to the URL.
+	// It returns ErrAlreadyListening if called twice and ErrClosed once Terminate has been called.
+	Listen(ctx context.Context) error
 
 	// Notify queues the messages into the Message Channel
 	Notify(messages []string) (string, error)
 
+	// NotifyContext queues the messages into the Message Channel before returning, respecting the deadline and
+	// cancellation of ctx. Under the OverflowReject policy it returns ErrQueueFull when the Message Channel is full.
+	NotifyContext(ctx context.Context, messages []string) (string, error)
+
+	// NotifyMessages behaves like NotifyContext for notifications carrying headers, metadata or binary payloads
+	NotifyMessages(ctx context.Context, notifications []Notification) (string, error)
+
+	// SendSync delivers the message inline, bypassing the Message Channel, and returns the response of the receiver
+	SendSync(ctx context.Context, msg string) (Result, error)
+
+	// SendMessageSync behaves like SendSync for a notification carrying headers, metadata or binary payloads
+	SendMessageSync(ctx context.Context, n Notification) (Result, error)
+
 	// Retry queue a message structure into the Message Channel
 	Retry(msg, guid string, index, numRetrials int)
 
+	// RetryNotification queues a failed notification, as reported in NError.Notification, into the Message Channel
+	RetryNotification(n Notification, guid string, index, numRetrials int)
+
+	// Cancel prevents the not-yet-sent messages of the batch identified by guid from being dispatched or retried.
+	// If indexes are provided, only those messages of the batch are cancelled. A failed message is counted in
+	// CancelResult.Failed once it is given up with DeadLetter, see GetErrorChannel.
+	Cancel(guid string, indexes ...int) (CancelResult, error)
+
 	// Terminate indicates the library that the client will stop the application and it has to flush the existing notifications contained in the Message Channel.
 	// Moreover, once Terminate is called, notilib will not accept new notifications.
-	Terminate(timeout time.Duration) <-chan bool
+	// The returned channel receives a value once the Message Channel is flushed or the timeout expires, then the
+	// transports created by notilib are closed. Calling Terminate twice returns ErrClosed.
+	Terminate(timeout time.Duration) (<-chan bool, error)
 
-	// Retrieves the receive-only Error Channel for reading operations (to be able to handle those errors)
+	// Retrieves the receive-only Error Channel for reading operations (to be able to handle those errors).
+	// Every error must be followed by RetryNotification or DeadLetter: until then the message is tracked for
+	// Cancel, so a client that gives up without calling DeadLetter keeps its batch tracked forever.
 	GetErrorChannel() <-chan NError
+
+	// Retrieves the receive-only Success Channel, reporting every notification accepted by the receiver.
+	// It is nil unless Config.SuccessChanCap is set, and it must be read as the deliveries wait once it is full.
+	GetSuccessChannel() <-chan NSuccess
+
+	// Retrieves the receive-only Discard Channel, reporting every queued notification cancelled or expired.
+	// It is nil unless Config.DiscardChanCap is set, and it must be read as the deliveries wait once it is full.
+	GetDiscardChannel() <-chan NDiscard
+
+	// Stats returns a snapshot of the state, the queue and the deliveries of the instance
+	Stats() Stats
+
+	// Pause stops dequeuing messages from the Message Channel, the notifications keep being queued.
+	// When the Message Channel is full, Config.PausedOverflow decides whether to block, reject or spill to disk.
+	Pause() error
+
+	// Resume restarts the delivery of the messages queued in the Message Channel, followed by the spilled ones
+	Resume() error
+
+	// SetRate changes the maximal number of messages to be processed per second
+	SetRate(numMessagesPerSecond int) error
+
+	// SetLogLevel changes the level of the logger, it is supported by the default and the logrus loggers
+	SetLogLevel(level log.Level) error
+
+	// Flush sends the messages waiting in the Message Channel right away, without waiting for the rate limiter,
+	// until the channel is empty or ctx is done. It returns the number of messages flushed.
+	Flush(ctx context.Context) (int, error)
+
+	// SetEndpoint replaces the URL, the HTTP method and the client used for delivering the notifications.
+	// The queued messages are kept and delivered to the new endpoint. It is not supported with Config.Transport.
+	SetEndpoint(url, method string, client *http.Client) error
+
+	// SetHeader replaces the default headers sent with every notification
+	SetHeader(header http.Header)
+
+	// DeadLetter stores a notification that exhausted its retrials, so it can be replayed later.
+	// When the dead letter queue is full, the oldest notification is dropped, and nothing is stored when it is
+	// disabled (Config.DeadLetterCap 0), the failure is only logged. A notification cancelled while it
+	// waited for this decision is not stored: it is reported to the Discard Channel and DeadLetter returns false.
+	DeadLetter(e NError) bool
+
+	// DeadLetters returns the notifications stored in the dead letter queue
+	DeadLetters() []NError
+
+	// ReplayDeadLetters queues again the notifications of the dead letter queue, starting again from the first
+	// attempt, and empties it. It returns the number of notifications queued.
+	ReplayDeadLetters() (int, error)
 }
 
 type notilib struct {
+	msgCh     chan message
 	errCh     chan NError
+	successCh chan NSuccess // nil if disabled
+	discardCh chan NDiscard // nil if disabled
 	listener  Listener
+	sender    sender
 	notifier  Notifier
 	retrialer Retrialer
-	state     status
+	tracker   tracker
+	closer    io.Closer // transport created by notilib, closed on terminate
+	custom    bool      // the transport has been provided by Config.Transport
+	log       Logger
+	stats     *statsRecorder
+	dead      *deadLetters
+
+	mu           sync.Mutex
+	state        status
+	stopListener context.CancelFunc // stops the listener goroutine
+	listenerDone chan struct{}      // closed when the listener goroutine returns
+}
+
+// instruments groups the logger, the metrics and the tracer shared by the components
+type instruments struct {
+	log      Logger
+	metrics  Metrics
+	tracer   trace.Tracer
+	stats    *statsRecorder
+	discards chan NDiscard // nil if the discarded notifications are not reported
+}
+
+// reportDiscard publishes the discarded notification into the Discard Channel, if enabled
+func (in instruments) reportDiscard(msg message, reason string) {
+	if in.discards == nil {
+		return
+	}
+	in.discards <- NDiscard{
+		GUID:         msg.guid,
+		Index:        msg.index,
+		Reason:       reason,
+		Notification: msg.notification,
+	}
 }
 
 type status int
 
+// The lifecycle of an instance is idle → listening → draining → closed.
+// Terminate can also be called on an idle instance, flushing the notifications queued so far.
 const (
-	idle        status = iota // waiting to listen
-	listening                 // listening notifications from Message Channel to be processed
-	terminating               // the program is finishing
+	idle      status = iota // waiting to listen, notifications are buffered in the Message Channel
+	listening               // listening notifications from Message Channel to be processed
+	draining                // flushing the Message Channel, new notifications are rejected
+	closed                  // terminated, the transports are closed
 )
 
-// New creates a new object that implements Notilib interface
+// New creates a new object that implements Notilib interface.
+// The url can be a text/template rendered for each message, e.g. "http://localhost/resources/{{.Metadata.id}}",
+// or use the tcp://, unix:// or grpc:// schemes for delivering the notifications over other transports.
+// The url and the client are ignored when conf.Transport is set.
 func New(url string, client *http.Client, conf *Config) (Notilib, error) {
 
 	// if no configuration is provided, build a default configuration
 	conf = getConfiguration(conf)
 
-	// logrus configuration
-	initLogger(conf.LogLevel)
-	log.Debugf("Notilib configuration: \n%v\n", conf)
-
-	// validate the URL format
-	err := checkURLFormat(url)
-	if err != nil {
-		return nil, err
-	}
+	conf.Logger.Debug("notilib configuration", "config", conf.String())
 
 	// create channels
 	msgChan := make(chan message, conf.MsgChanCap)
 	errCh := make(chan NError, conf.ErrChanCap)
+	var successCh chan NSuccess
+	if conf.SuccessChanCap > 0 {
+		successCh = make(chan NSuccess, conf.SuccessChanCap)
+	}
+	var discardCh chan NDiscard
+	if conf.DiscardChanCap > 0 {
+		discardCh = make(chan NDiscard, conf.DiscardChanCap)
+	}
+
+	// create a tracker to keep the state of every batch of messages
+	tracker := newTracker()
+
+	// the logger, the metrics and the tracer are shared by all the components,
+	// the metrics are counted for Stats before being forwarded to conf.Metrics
+	stats := newStatsRecorder(conf.Metrics)
+	in := instruments{
+		log:      conf.Logger,
+		metrics:  stats,
+		tracer:   conf.TracerProvider.Tracer(tracerName),
+		stats:    stats,
+		discards: discardCh,
+	}
+
+	// create a sender, validating the URL
+	transport, closer, err := buildTransport(url, client, conf)
+	if err != nil {
+		return nil, err
+	}
+	sender := newSender(transport, conf.Header, conf.Redactor, errCh, successCh, tracker, in)
 
 	// create a listener
-	listener, err := buildListener(url, conf, client, msgChan, errCh)
+	listener, err := buildListener(conf, msgChan, sender, in)
 	if err != nil {
 		return nil, err
 	}
 
-	// create a notifier
-	notifier, err := newNotifier(msgChan)
+	// create a notifier, spilling to disk while paused if requested
+	var sp *spool
+	if conf.PausedOverflow == OverflowSpill {
+		sp = newSpool(conf.SpillDir)
+	}
+	notifier, err := newNotifier(msgChan, tracker, conf.Overflow, conf.PausedOverflow, sp, in)
 	if err != nil {
 		return nil, err
 	}
 
 	// create a retrialer
-	retrialer, err := newRetrialer(msgChan)
+	retrialer, err := newRetrialer(msgChan, tracker, in)
 	if err != nil {
 		return nil, err
 	}
 
 	notilib := &notilib{
+		msgCh:     msgChan,
 		errCh:     errCh,
+		successCh: successCh,
+		discardCh: discardCh,
 		listener:  listener,
+		sender:    sender,
 		notifier:  notifier,
 		retrialer: retrialer,
+		tracker:   tracker,
+		closer:    closer,
+		custom:    conf.Transport != nil,
+		log:       conf.Logger,
+		stats:     stats,
+		dead:      newDeadLetters(conf.DeadLetterCap),
 		state:     idle,
 	}
 
 	return notilib, nil
 }
 
-func buildListener(url string, conf *Config, client *http.Client, msgChan chan message, errCh chan NError) (Listener, error) {
-	if client == nil {
-		client = http.DefaultClient
+// buildTransport returns the transport for delivering the notifications and, when it has been created by
+// notilib and holds connections, the closer to release them on terminate
+func buildTransport(url string, client *http.Client, conf *Config) (Transport, io.Closer, error) {
+	if conf.Transport != nil {
+		return conf.Transport, nil, nil
+	}
+	transport, err := newTransport(url, conf.Method, client)
+	if err != nil {
+		return nil, nil, err
 	}
-	clientHandler := newClientHandler(client)
-	sender := newSender(url, clientHandler, errCh)
-	rate := time.Second / time.Duration(conf.NumMessagesPerSecond)
-	listener, err := newListener(rate, conf.BurstLimit, msgChan, sender)
+	closer, _ := transport.(io.Closer)
+	return transport, closer, nil
+}
+
+func buildListener(conf *Config, msgChan chan message, sender sender, in instruments) (Listener, error) {
+	listener, err := newListener(rateInterval(conf.NumMessagesPerSecond), conf.BurstLimit, msgChan, sender, in)
 	if err != nil {
 		return nil, fmt.Errorf("unable to initialize notilib: %v", err)
 	}
 	return listener, err
 }
 
+// rateInterval returns the time between two messages to process numMessagesPerSecond
+func rateInterval(numMessagesPerSecond int) time.Duration {
+	return time.Second / time.Duration(numMessagesPerSecond)
+}
+
+// validRate reports whether numMessagesPerSecond gives an interval the rate limiter can tick at
+func validRate(numMessagesPerSecond int) bool {
+	return numMessagesPerSecond > 0 && rateInterval(numMessagesPerSecond) > 0
+}
+
+// accepting returns ErrClosed once Terminate has been called
+func (n *notilib) accepting() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.state == draining || n.state == closed {
+		return ErrClosed
+	}
+	return nil
+}
+
 func (n *notilib) Notify(messages []string) (string, error) {
-	if n.state == terminating {
-		return "", fmt.Errorf("the application is terminating, it does not accept new notifications")
+	if err := n.accepting(); err != nil {
+		return "", err
 	}
-	return n.notifier.notify(messages)
+	return n.notifier.notify(textNotifications(messages))
+}
+
+func (n *notilib) NotifyContext(ctx context.Context, messages []string) (string, error) {
+	return n.NotifyMessages(ctx, textNotifications(messages))
+}
+
+func (n *notilib) NotifyMessages(ctx context.Context, notifications []Notification) (string, error) {
+	if err := n.accepting(); err != nil {
+		return "", err
+	}
+	return n.notifier.notifyContext(ctx, notifications)
+}
+
+func (n *notilib) SendSync(ctx context.Context, msg string) (Result, error) {
+	return n.SendMessageSync(ctx, Notification{Body: []byte(msg)})
+}
+
+func (n *notilib) SendMessageSync(ctx context.Context, notification Notification) (Result, error) {
+	if err := n.accepting(); err != nil {
+		return Result{}, err
+	}
+	guid, err := newGUID()
+	if err != nil {
+		return Result{}, err
+	}
+	return n.sender.deliver(ctx, newMessage(notification, guid, 0, trace.SpanContext{}))
 }
 
 func (n *notilib) Retry(msg, guid string, index, numRetrials int) {
-	n.retrialer.retry(msg, guid, index, numRetrials)
+	n.RetryNotification(Notification{Body: []byte(msg)}, guid, index, numRetrials)
+}
+
+func (n *notilib) RetryNotification(notification Notification, guid string, index, numRetrials int) {
+	n.mu.Lock()
+	state := n.state
+	n.mu.Unlock()
+
+	// nobody reads the Message Channel anymore
+	if state == closed {
+		n.log.Warn("retrial discarded, notilib is closed", guidField, guid, indexField, index)
+		return
+	}
+	n.retrialer.retry(notification, guid, index, numRetrials)
+}
+
+func (n *notilib) Cancel(guid string, indexes ...int) (CancelResult, error) {
+	res, err := n.tracker.cancel(guid, indexes)
+	if err != nil {
+		return res, err
+	}
+	n.log.Info("batch cancelled", guidField, guid, "cancelled", res.Cancelled, "delivered", res.Delivered)
+	return res, nil
 }
 
-func (n *notilib) Listen(ctx context.Context) {
+func (n *notilib) Listen(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch n.state {
+	case listening:
+		return ErrAlreadyListening
+	case draining, closed:
+		return ErrClosed
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
 	n.state = listening
-	go n.listener.listen(ctx)
+	n.stopListener = cancel
+	n.listenerDone = done
+
+	go func() {
+		defer close(done)
+		n.listener.listen(ctx)
+	}()
+	return nil
 }
 
-func (n *notilib) Terminate(timeout time.Duration) <-chan bool {
-	n.state = terminating
-	quit := make(chan bool)
+func (n *notilib) Terminate(timeout time.Duration) (<-chan bool, error) {
+	n.mu.Lock()
+	if n.state == draining || n.state == closed {
+		n.mu.Unlock()
+		return nil, ErrClosed
+	}
+	n.state = draining
+	stop, stopped := n.stopListener, n.listenerDone
+	n.mu.Unlock()
+
+	quit := make(chan bool, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		// the listener must not compete with the flush for the remaining messages
+		if stop != nil {
+			stop()
+			<-stopped
+		}
+
+		flushed := make(chan struct{})
+		go func() {
+			defer close(flushed)
+			// the spilled messages are moved into the Message Channel as it is flushed
+			for {
+				n.listener.flush(ctx)
+				if ctx.Err() != nil || n.notifier.unspill() == 0 {
+					return
+				}
+			}
+		}()
+		select {
+		case <-flushed:
+		case <-ctx.Done():
+		}
+
+		n.close()
+		quit <- true
+	}()
+
+	return quit, nil
+}
 
-	go func(quit chan<- bool) {
-		n.listener.flush(timeout, quit)
-	}(quit)
+// close releases the transport and moves the instance to the closed state
+func (n *notilib) close() {
+	n.mu.Lock()
+	n.state = closed
+	closer := n.closer
+	n.mu.Unlock()
 
-	return quit
+	if spilled := n.notifier.spilled(); spilled > 0 {
+		n.log.Warn("spilled messages not delivered", messagesField, spilled)
+	}
+	if closer == nil {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		n.log.Warn("unable to close the transport", errorField, err)
+	}
 }
 
 func (n *notilib) GetErrorChannel() <-chan NError {
 	return n.errCh
 }
 
-func checkURLFormat(url string) error {
-	if url == "" {
-		return fmt.Errorf("empty URL")
+func (n *notilib) GetSuccessChannel() <-chan NSuccess {
+	return n.successCh
+}
+
+func (n *notilib) GetDiscardChannel() <-chan NDiscard {
+	return n.discardCh
+}
+
+func (n *notilib) Pause() error {
+	if err := n.accepting(); err != nil {
+		return err
 	}
-	_, err := neturl.ParseRequestURI(url)
+	n.notifier.pause()
+	n.listener.pause()
+	n.log.Info("delivery paused", messagesField, len(n.msgCh))
+	return nil
+}
+
+func (n *notilib) Resume() error {
+	if err := n.accepting(); err != nil {
+		return err
+	}
+	n.listener.resume()
+	n.notifier.resume()
+	n.log.Info("delivery resumed", messagesField, len(n.msgCh), "spilled", n.notifier.spilled())
+	return nil
+}
+
+func (n *notilib) SetRate(numMessagesPerSecond int) error {
+	if !validRate(numMessagesPerSecond) {
+		return fmt.Errorf("invalid rate: %d, it must be between 1 and %d", numMessagesPerSecond, MaxNumMessagesPerSecond)
+	}
+	n.listener.setRate(rateInterval(numMessagesPerSecond))
+	n.log.Info("rate changed", "messagesPerSecond", numMessagesPerSecond)
+	return nil
+}
+
+func (n *notilib) SetLogLevel(level log.Level) error {
+	setter, ok := n.log.(levelSetter)
+	if !ok {
+		return fmt.Errorf("the logger does not support changing the level")
+	}
+	if err := setter.setLevel(level); err != nil {
+		return err
+	}
+	n.log.Info("log level changed", "level", level.String())
+	return nil
+}
+
+func (n *notilib) Flush(ctx context.Context) (int, error) {
+	if err := n.accepting(); err != nil {
+		return 0, err
+	}
+	return n.listener.flush(ctx), nil
+}
+
+func (n *notilib) SetEndpoint(url, method string, client *http.Client) error {
+	if n.custom {
+		return fmt.Errorf("the endpoint of a custom transport can not be changed")
+	}
+	if method == "" {
+		method = defaultMethod
+	}
+	transport, err := newTransport(url, method, client)
 	if err != nil {
-		return fmt.Errorf("invalid URL")
+		return err
+	}
+
+	n.mu.Lock()
+	if n.state == draining || n.state == closed {
+		n.mu.Unlock()
+		if c, ok := transport.(io.Closer); ok {
+			c.Close()
+		}
+		return ErrClosed
 	}
+	previous := n.closer
+	n.closer, _ = transport.(io.Closer)
+	n.sender.setTransport(transport)
+	n.mu.Unlock()
+
+	// a delivery in progress over the previous connection fails and is reported to the Error Channel
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			n.log.Warn("unable to close the transport", errorField, err)
+		}
+	}
+	n.log.Info("endpoint changed", "url", url, "method", method)
 	return nil
 }
 
-func initLogger(logLevel log.Level) {
-	formatter := &log.TextFormatter{
-		FullTimestamp: true,
+func (n *notilib) SetHeader(header http.Header) {
+	n.sender.setHeader(header.Clone())
+	n.log.Info("default headers changed")
+}
+
+func (n *notilib) DeadLetter(e NError) bool {
+	// a cancelled message has already been reported by Cancel, it must not be counted as failed too
+	msg := message{notification: e.Notification, guid: e.GUID, index: e.Index, numRetrials: e.NumRetrials}
+	if n.tracker.dropCancelled(msg) {
+		n.log.Debug("dead letter discarded, message cancelled", guidField, e.GUID, indexField, e.Index)
+		if n.discardCh != nil {
+			n.discardCh <- NDiscard{GUID: e.GUID, Index: e.Index, Reason: DiscardCancelled, Notification: e.Notification}
+		}
+		return false
+	}
+
+	n.tracker.failed(e.GUID, e.Index)
+	if n.dead.capacity <= 0 {
+		n.log.Warn("notification dropped, the dead letter queue is disabled", guidField, e.GUID, indexField, e.Index, errorField, e.ErrorMessage)
+		return true
+	}
+	if n.dead.add(e) {
+		n.log.Warn("dead letter queue full, oldest notification dropped")
+	}
+	n.log.Warn("notification moved to the dead letter queue", guidField, e.GUID, indexField, e.Index, errorField, e.ErrorMessage)
+	return true
+}
+
+func (n *notilib) DeadLetters() []NError {
+	return n.dead.list()
+}
+
+func (n *notilib) ReplayDeadLetters() (int, error) {
+	if err := n.accepting(); err != nil {
+		return 0, err
 	}
-	log.SetFormatter(formatter)
-	log.SetLevel(logLevel)
+	letters := n.dead.take()
+	for _, e := range letters {
+		// the retrialer counts one more retrial, so the notification starts again from the first attempt
+		n.tracker.replayed(e.GUID, e.Index)
+		n.retrialer.retry(e.Notification, e.GUID, e.Index, -1)
+	}
+	n.log.Info("dead letters replayed", messagesField, len(letters))
+	return len(letters), nil
+}
+
+func (n *notilib) Stats() Stats {
+	stats := n.stats.snapshot()
+	stats.QueueLength = len(n.msgCh)
+	stats.QueueCapacity = cap(n.msgCh)
+	stats.Paused = n.listener.isPaused()
+	stats.DeadLetters = n.dead.len()
+	stats.Spilled = n.notifier.spilled()
+
+	n.mu.Lock()
+	stats.State = n.state.String()
+	n.mu.Unlock()
+	return stats
+}
+
+func (s status) String() string {
+	switch s {
+	case idle:
+		return "idle"
+	case listening:
+		return "listening"
+	case draining:
+		return "draining"
+	case closed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+func checkURLFormat(url string) (*neturl.URL, error) {
+	if url == "" {
+		return nil, fmt.Errorf("empty URL")
+	}
+	target, err := neturl.ParseRequestURI(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL")
+	}
+	return target, nil
 }
 
 func getConfiguration(conf *Config) *Config {
 	if conf == nil {
-		return DefaultConfig()
+		conf = DefaultConfig()
 	}
 	if conf.BurstLimit < 0 {
 		conf.BurstLimit = defaultBurstLimit
@@ -177,7 +652,28 @@ func getConfiguration(conf *Config) *Config {
 	if conf.ErrChanCap < 0 {
 		conf.ErrChanCap = defaultErrChCap
 	}
-	if conf.NumMessagesPerSecond < 0 {
+	if conf.Overflow != OverflowBlock && conf.Overflow != OverflowReject {
+		conf.Overflow = defaultOverflow
+	}
+	if conf.Logger == nil {
+		conf.Logger = newDefaultLogger(os.Stderr, conf.LogLevel)
+	}
+	if conf.TracerProvider == nil {
+		conf.TracerProvider = otel.GetTracerProvider()
+	}
+	if conf.Metrics == nil {
+		conf.Metrics = noopMetrics{}
+	}
+	if conf.Method == "" {
+		conf.Method = defaultMethod
+	}
+	if conf.PausedOverflow != OverflowBlock && conf.PausedOverflow != OverflowReject && conf.PausedOverflow != OverflowSpill {
+		conf.PausedOverflow = defaultPausedOverflow
+	}
+	if conf.DeadLetterCap < 0 {
+		conf.DeadLetterCap = defaultDeadLetterCap
+	}
+	if !validRate(conf.NumMessagesPerSecond) {
 		conf.NumMessagesPerSecond = defaultNumMessagesPerSecond
 	}
 	return conf
@@ -1,8 +1,16 @@
 package notilib
 
 import (
+	"context"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 func TestNew(t *testing.T) {
@@ -59,3 +67,309 @@ func checkError(errMsg string, err error, t *testing.T) bool {
 	}
 	return false
 }
+
+func TestLifecycle(t *testing.T) {
+	received := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received <- string(body)
+	}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.Logger = testInstruments.log
+	notilib, err := New(server.URL, nil, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// notifications are buffered while idle
+	if _, err := notilib.Notify([]string{"first"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := notilib.Listen(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := notilib.Listen(ctx); err != ErrAlreadyListening {
+		t.Errorf("expected %v; got %v", ErrAlreadyListening, err)
+	}
+
+	quit, err := notilib.Terminate(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := notilib.Terminate(time.Second); err != ErrClosed {
+		t.Errorf("expected %v; got %v", ErrClosed, err)
+	}
+	if _, err := notilib.Notify([]string{"second"}); err != ErrClosed {
+		t.Errorf("expected %v; got %v", ErrClosed, err)
+	}
+	if _, err := notilib.SendSync(ctx, "second"); err != ErrClosed {
+		t.Errorf("expected %v; got %v", ErrClosed, err)
+	}
+
+	select {
+	case <-quit:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("terminate did not finish")
+	}
+	if err := notilib.Listen(ctx); err != ErrClosed {
+		t.Errorf("expected %v; got %v", ErrClosed, err)
+	}
+	if stats := notilib.Stats(); stats.State != "closed" || stats.Enqueued != 1 {
+		t.Errorf("expected closed state with 1 message enqueued; got: %+v", stats)
+	}
+	if body := <-received; body != "first" {
+		t.Errorf("expected first; got %s", body)
+	}
+}
+
+func TestConcurrentInstances(t *testing.T) {
+	var received int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&received, 1)
+	}))
+	defer server.Close()
+
+	const numInstances = 3
+	const numMessages = 20
+
+	var wg sync.WaitGroup
+	for i := 0; i < numInstances; i++ {
+		conf := DefaultConfig()
+		conf.Logger = testInstruments.log
+		notilib, err := New(server.URL, nil, conf)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// listen, notify and terminate race against each other
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			notilib.Listen(context.Background())
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < numMessages; j++ {
+				if _, err := notilib.Notify([]string{"hello"}); err != nil && err != ErrClosed {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			time.Sleep(10 * time.Millisecond)
+			quit, err := notilib.Terminate(time.Second)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			<-quit
+		}()
+	}
+	wg.Wait()
+
+	if atomic.LoadInt64(&received) == 0 {
+		t.Errorf("no notification delivered")
+	}
+}
+
+func TestRuntimeOperations(t *testing.T) {
+	conf := DefaultConfig()
+	conf.Logger = newDefaultLogger(ioutil.Discard, logrus.InfoLevel)
+	notilib, err := New("http://localhost/api", nil, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checkError("invalid rate: 0, it must be between 1 and 1000000000", notilib.SetRate(0), t)
+	checkError("invalid rate: 1000000001, it must be between 1 and 1000000000", notilib.SetRate(MaxNumMessagesPerSecond+1), t)
+	checkError("", notilib.SetRate(MaxNumMessagesPerSecond), t)
+	checkError("", notilib.SetRate(10), t)
+	checkError("", notilib.SetLogLevel(logrus.DebugLevel), t)
+
+	if err := notilib.Pause(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !notilib.Stats().Paused {
+		t.Errorf("expected paused state")
+	}
+
+	notilib.DeadLetter(NError{GUID: "111-222-333-444", Index: 3, NumRetrials: 2, Notification: Notification{Body: []byte("body content")}})
+	if letters := notilib.DeadLetters(); len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter; got %d", len(letters))
+	}
+	replayed, err := notilib.ReplayDeadLetters()
+	if err != nil || replayed != 1 {
+		t.Fatalf("expected 1 dead letter replayed; got %d, %v", replayed, err)
+	}
+	stats := notilib.Stats()
+	if stats.DeadLetters != 0 || stats.QueueLength != 1 {
+		t.Errorf("expected the dead letter to be queued again; got %+v", stats)
+	}
+
+	quit, err := notilib.Terminate(time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-quit
+	if err := notilib.Resume(); err != ErrClosed {
+		t.Errorf("expected %v; got %v", ErrClosed, err)
+	}
+	if _, err := notilib.Flush(context.Background()); err != ErrClosed {
+		t.Errorf("expected %v; got %v", ErrClosed, err)
+	}
+}
+
+func TestSetEndpoint(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	before := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected notification sent to the previous endpoint")
+	}))
+	defer before.Close()
+	after := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer after.Close()
+
+	conf := DefaultConfig()
+	conf.Logger = newDefaultLogger(ioutil.Discard, logrus.InfoLevel)
+	notilib, err := New(before.URL, nil, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer notilib.Terminate(time.Second)
+
+	// the message queued while paused is delivered to the new endpoint
+	notilib.Pause()
+	if _, err := notilib.NotifyContext(context.Background(), []string{"body content"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkError("invalid URL", notilib.SetEndpoint("localhost", "", nil), t)
+	checkError("", notilib.SetEndpoint(after.URL, http.MethodPut, nil), t)
+	notilib.SetHeader(http.Header{"Authorization": {"Bearer s3cret"}})
+	notilib.Listen(context.Background())
+	notilib.Resume()
+
+	select {
+	case r := <-received:
+		if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer s3cret" {
+			t.Errorf("expected PUT with the new header; got %s %v", r.Method, r.Header)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("notification not delivered to the new endpoint")
+	}
+
+	custom := DefaultConfig()
+	custom.Logger = conf.Logger
+	custom.Transport = newHTTPTransport(mustEndpoint("http://localhost"), &MockDispatcher{})
+	notilib, err = New("", nil, custom)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	checkError("the endpoint of a custom transport can not be changed", notilib.SetEndpoint(after.URL, "", nil), t)
+}
+
+func TestSuccessChannel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.Logger = testInstruments.log
+	notilib, err := New(server.URL, nil, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if notilib.GetSuccessChannel() != nil {
+		t.Errorf("expected the Success Channel to be disabled by default")
+	}
+
+	conf.SuccessChanCap = 2
+	notilib, err = New(server.URL, nil, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := notilib.Listen(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	guid, err := notilib.Notify([]string{"first", "second"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	delivered := map[int]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case s := <-notilib.GetSuccessChannel():
+			if s.GUID != guid || s.StatusCode != http.StatusOK {
+				t.Errorf("expected the delivery of %s with status 200; got %+v", guid, s)
+			}
+			delivered[s.Index] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("the deliveries have not been reported")
+		}
+	}
+	if !delivered[0] || !delivered[1] {
+		t.Errorf("expected the deliveries of the indexes 0 and 1; got %v", delivered)
+	}
+}
+
+func TestDeadLetterCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	conf := DefaultConfig()
+	conf.Logger = testInstruments.log
+	conf.DiscardChanCap = 1
+	notilib, err := New(server.URL, nil, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := notilib.Listen(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	guid, err := notilib.Notify([]string{"first"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var e NError
+	select {
+	case e = <-notilib.GetErrorChannel():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("the failure has not been reported")
+	}
+	// the message is cancelled while it waits for a retrial, so it is not a dead letter
+	if res, err := notilib.Cancel(guid); err != nil || res.Cancelled != 1 {
+		t.Fatalf("expected 1 message cancelled; got %+v, %v", res, err)
+	}
+	if notilib.DeadLetter(e) {
+		t.Errorf("expected the cancelled message not to be stored")
+	}
+	if letters := notilib.DeadLetters(); len(letters) != 0 {
+		t.Errorf("expected no dead letters; got %d", len(letters))
+	}
+	select {
+	case d := <-notilib.GetDiscardChannel():
+		if d.GUID != guid || d.Reason != DiscardCancelled {
+			t.Errorf("expected the cancellation of %s; got %+v", guid, d)
+		}
+	default:
+		t.Errorf("the cancellation has not been reported")
+	}
+
+	// the batch is finished, so Cancel does not count the message twice
+	if res, err := notilib.Cancel(guid); err != nil || res != (CancelResult{GUID: guid}) {
+		t.Errorf("expected nothing to cancel; got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,11 @@
+package notilib
+
+// NSuccess struct sent to the Success Channel once the receiver has accepted a notification
+type NSuccess struct {
+	GUID         string       // GUID: Unique identifier
+	Index        int          // Index of the message from the slice passed as parameter to the notilib.Notify method
+	NumRetrials  int          // Number of retrials before the delivery
+	StatusCode   int          // Status code returned by the receiver (HTTP status or gRPC code)
+	Status       string       // Status text returned by the receiver
+	Notification Notification // Delivered notification
+}
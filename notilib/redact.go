@@ -0,0 +1,259 @@
+package notilib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// RedactionMode defines how the Redactor replaces the sensitive values
+type RedactionMode int
+
+const (
+	RedactMask RedactionMode = iota // replace the value with the mask of its rule, e.g. [EMAIL]
+	RedactHash                      // replace the value with a salted hash, e.g. [EMAIL:9f86d081884c], keeping equal values correlated
+)
+
+func (m RedactionMode) String() string {
+	switch m {
+	case RedactMask:
+		return "mask"
+	case RedactHash:
+		return "hash"
+	}
+	return fmt.Sprintf("RedactionMode(%d)", int(m))
+}
+
+// redactionHashLen is the number of hexadecimal characters of the hash kept by RedactHash
+const redactionHashLen = 12
+
+// RedactionRule detects a kind of sensitive value in the body of the notifications
+type RedactionRule struct {
+	Name     string                  // Name of the rule, used by the counters and the default mask
+	Pattern  *regexp.Regexp          // Values to redact, only the first group is replaced if the pattern has groups
+	Validate func(value string) bool // Discards false positives of the pattern (optional)
+	Mask     string                  // Replacement under RedactMask, by default the upper case name between brackets
+}
+
+// Bundled redaction rules
+var (
+	// EmailRule detects email addresses
+	EmailRule = RedactionRule{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	}
+
+	// IPv4Rule detects IPv4 addresses
+	IPv4Rule = RedactionRule{
+		Name:    "ipv4",
+		Pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+	}
+
+	// IPv6Rule detects IPv6 addresses with two groups at least, the candidates that do not parse as such
+	// (e.g. times) are kept
+	IPv6Rule = RedactionRule{
+		Name:     "ipv6",
+		Pattern:  regexp.MustCompile(`(?i)(?:^|[^0-9a-z:])((?:[0-9a-f]{1,4})?(?::[0-9a-f]{0,4}){2,7})`),
+		Validate: validIPv6,
+	}
+
+	// JWTRule detects JSON Web Tokens
+	JWTRule = RedactionRule{
+		Name:    "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	}
+
+	// CreditCardRule detects card numbers, optionally separated by spaces or dashes, passing the Luhn check
+	CreditCardRule = RedactionRule{
+		Name:     "credit_card",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: validLuhn,
+	}
+
+	// AWSAccessKeyRule detects AWS access key IDs
+	AWSAccessKeyRule = RedactionRule{
+		Name:    "aws_access_key",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA|ANPA|ANVA|AIPA)[A-Z0-9]{16}\b`),
+	}
+
+	// AWSSecretKeyRule detects AWS secret access keys assigned to a key named like them
+	AWSSecretKeyRule = RedactionRule{
+		Name:    "aws_secret_key",
+		Pattern: regexp.MustCompile(`(?i)\baws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`),
+	}
+)
+
+// DefaultRedactionRules returns the bundled rules, ordered so the longer values are redacted as a whole
+func DefaultRedactionRules() []RedactionRule {
+	return []RedactionRule{JWTRule, AWSSecretKeyRule, AWSAccessKeyRule, EmailRule, CreditCardRule, IPv4Rule, IPv6Rule}
+}
+
+// Redactor replaces the sensitive values of the notifications before they are encoded for the transport.
+// It is safe for concurrent use.
+type Redactor struct {
+	mode   RedactionMode
+	salt   string
+	rules  []RedactionRule
+	counts []uint64 // messages redacted by every rule
+}
+
+// NewRedactor creates a Redactor applying the rules in order. The salt is prepended to the values hashed by
+// RedactHash, so they can not be guessed from a dictionary.
+func NewRedactor(mode RedactionMode, salt string, rules ...RedactionRule) (*Redactor, error) {
+	if mode != RedactMask && mode != RedactHash {
+		return nil, fmt.Errorf("invalid redaction mode: %v", mode)
+	}
+	names := map[string]bool{}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("redaction rule %d has no name", i)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("duplicated redaction rule: %s", rule.Name)
+		}
+		if rule.Pattern == nil {
+			return nil, fmt.Errorf("redaction rule %s has no pattern", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return &Redactor{
+		mode:   mode,
+		salt:   salt,
+		rules:  rules,
+		counts: make([]uint64, len(rules)),
+	}, nil
+}
+
+// Counts returns the number of messages with values redacted by every rule, counted once per message
+func (r *Redactor) Counts() map[string]uint64 {
+	counts := make(map[string]uint64, len(r.rules))
+	for i, rule := range r.rules {
+		counts[rule.Name] = atomic.LoadUint64(&r.counts[i])
+	}
+	return counts
+}
+
+// Redact returns a copy of the notification with the sensitive values of its body replaced.
+// The binary bodies, identified by their content type, are not modified.
+func (r *Redactor) Redact(n Notification) Notification {
+	return r.redact(n, true)
+}
+
+// redact is Redact, counting the rules that redact any value of the notification if count is set. The retrials
+// of a message are redacted again without counting them.
+func (r *Redactor) redact(n Notification, count bool) Notification {
+	if r == nil || len(n.Body) == 0 || !textContent(n.ContentType) {
+		return n
+	}
+	body := string(n.Body)
+	for i := range r.rules {
+		var redacted bool
+		body, redacted = r.apply(i, body)
+		if redacted && count {
+			atomic.AddUint64(&r.counts[i], 1)
+		}
+	}
+	n.Body = []byte(body)
+	return n
+}
+
+// apply replaces the values of the rule i in text, reporting whether any value has been replaced
+func (r *Redactor) apply(i int, text string) (string, bool) {
+	rule := r.rules[i]
+	matches := rule.Pattern.FindAllStringSubmatchIndex(text, -1)
+	if matches == nil {
+		return text, false
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		value := text[start:end]
+		if value == "" || (rule.Validate != nil && !rule.Validate(value)) {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(r.replacement(rule, value))
+		last = end
+	}
+	if last == 0 {
+		return text, false
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), true
+}
+
+func (r *Redactor) replacement(rule RedactionRule, value string) string {
+	name := strings.ToUpper(rule.Name)
+	if r.mode == RedactHash {
+		sum := sha256.Sum256([]byte(r.salt + value))
+		return "[" + name + ":" + hex.EncodeToString(sum[:])[:redactionHashLen] + "]"
+	}
+	if rule.Mask != "" {
+		return rule.Mask
+	}
+	return "[" + name + "]"
+}
+
+// textContent reports whether a body with the content type can be redacted, an empty one is considered text
+func textContent(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/x-www-form-urlencoded", "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// validIPv6 discards the candidates that are not IPv6 addresses or have less than two groups, e.g. std::
+func validIPv6(value string) bool {
+	groups := 0
+	for _, group := range strings.Split(value, ":") {
+		if group != "" {
+			groups++
+		}
+	}
+	return groups >= 2 && net.ParseIP(value) != nil
+}
+
+// validLuhn reports whether the digits of the value pass the Luhn checksum
+func validLuhn(value string) bool {
+	sum, digits := 0, 0
+	for i := len(value) - 1; i >= 0; i-- {
+		c := value[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
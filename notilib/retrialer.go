@@ -1,37 +1,68 @@
 package notilib
 
 import (
+	"context"
 	"fmt"
 
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Retrialer interface {
-	retry(content, guid string, index, numRetrials int)
+	retry(n Notification, guid string, index, numRetrials int)
 }
 
 type retrialer struct {
-	msgCh chan message
+	msgCh   chan message
+	tracker tracker
+	instruments
 }
 
-func newRetrialer(msgChan chan message) (Retrialer, error) {
+func newRetrialer(msgChan chan message, t tracker, in instruments) (Retrialer, error) {
 	if msgChan == nil {
 		return nil, fmt.Errorf("msgChan can not be nil")
 	}
+	if t == nil {
+		return nil, fmt.Errorf("tracker can not be nil")
+	}
 	return &retrialer{
-		msgCh: msgChan,
+		msgCh:       msgChan,
+		tracker:     t,
+		instruments: in,
 	}, nil
 }
 
-func (r *retrialer) retry(content, guid string, index, numRetrials int) {
+func (r *retrialer) retry(n Notification, guid string, index, numRetrials int) {
 	// update the number of retrials
 	retrials := numRetrials + 1
 
-	r.msgCh <- message{
-		content:     content,
-		guid:        guid,
-		index:       index,
-		numRetrials: retrials,
+	// the retrial belongs to the trace of the batch
+	sc := r.tracker.spanContext(guid)
+	ctx := withParentSpan(context.Background(), sc)
+	_, span := r.tracer.Start(ctx, retrySpanName, trace.WithAttributes(
+		guidAttribute.String(guid),
+		indexAttribute.Int(index),
+		attemptAttribute.Int(retrials),
+	))
+	defer span.End()
+
+	msg := message{
+		notification: n,
+		guid:         guid,
+		index:        index,
+		numRetrials:  retrials,
+		spanCtx:      sc,
 	}
-	log.Warnf("Retrial[%d]: { GUID : \"%s\", Index : %d, Content : \"%s\" }", retrials, guid, index, content)
+
+	// a cancelled message must not be retried
+	if r.tracker.dropCancelled(msg) {
+		r.log.Debug("retrial discarded, message cancelled", guidField, guid, indexField, index)
+		span.AddEvent("message cancelled")
+		r.reportDiscard(msg, DiscardCancelled)
+		return
+	}
+
+	r.msgCh <- msg
+	r.metrics.Retried()
+	r.metrics.QueueLength(len(r.msgCh))
+	r.log.Warn("retrial queued", guidField, guid, indexField, index, attemptField, retrials)
 }
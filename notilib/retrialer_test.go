@@ -3,6 +3,8 @@ package notilib
 import (
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestRetry(t *testing.T) {
@@ -21,11 +23,11 @@ func TestRetry(t *testing.T) {
 
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
-			retrialer, err := newRetrialer(tc.msgChan)
+			retrialer, err := newRetrialer(tc.msgChan, newTracker(), testInstruments)
 
 			if tc.msgChan != nil {
 				if !checkError(tc.errMsg, err, t) {
-					retrialer.retry(tc.content, tc.guid, tc.index, tc.numRetrials)
+					retrialer.retry(Notification{Body: []byte(tc.content)}, tc.guid, tc.index, tc.numRetrials)
 
 					// give some time to call send method
 					time.Sleep(1 * time.Second)
@@ -41,3 +43,23 @@ func TestRetry(t *testing.T) {
 		})
 	}
 }
+
+func TestRetryCancelled(t *testing.T) {
+	msgChan := make(chan message, 1)
+	tracker := newTracker()
+	tracker.register("1234", []int{0}, trace.SpanContext{})
+	tracker.cancel("1234", nil)
+
+	in := testInstruments
+	in.discards = make(chan NDiscard, 1)
+	retrialer, err := newRetrialer(msgChan, tracker, in)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	retrialer.retry(Notification{Body: []byte("hello world")}, "1234", 0, 1)
+
+	if len(msgChan) != 0 {
+		t.Errorf("cancelled message has been queued again")
+	}
+	checkDiscard(t, in.discards, message{guid: "1234", index: 0}, DiscardCancelled)
+}
@@ -1,58 +1,156 @@
 package notilib
 
 import (
-	"fmt"
+	"context"
 	"net/http"
-	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
-	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// Result is the response of the receiver to a notification sent synchronously
+type Result struct {
+	GUID       string // GUID: Unique identifier assigned to the notification
+	StatusCode int    // Status code returned by the receiver (HTTP status or gRPC code)
+	Status     string // Status text returned by the receiver
+	Body       []byte // Response body (truncated to 1MB)
+}
+
 type sender interface {
 	send(msg message)
+	deliver(ctx context.Context, msg message) (Result, error)
+	setTransport(transport Transport)
+	setHeader(header http.Header)
 }
 
 type senderHandler struct {
-	url    string
-	client dispatcher
-	errCh  chan NError
+	mu        sync.RWMutex // guards the transport and the header, they can be replaced at runtime
+	transport Transport
+	header    http.Header // headers sent with every notification, e.g. for authentication
+	redactor  *Redactor   // nil if the notifications are not redacted
+	errCh     chan NError
+	successCh chan NSuccess // nil if the deliveries are not reported
+	tracker   tracker
+	inFlight  int64 // number of deliveries waiting for the receiver
+	instruments
 }
 
-func newSender(url string, client dispatcher, errCh chan NError) sender {
+func newSender(transport Transport, header http.Header, redactor *Redactor, errCh chan NError, successCh chan NSuccess, t tracker, in instruments) sender {
 	return &senderHandler{
-		url:    url,
-		client: client,
-		errCh:  errCh,
+		transport:   transport,
+		header:      header,
+		redactor:    redactor,
+		errCh:       errCh,
+		successCh:   successCh,
+		tracker:     t,
+		instruments: in,
 	}
 }
 
-// send is responsible for sending the request to the client
+// send is responsible for sending the message through the transport
 func (f *senderHandler) send(msg message) {
-	body := strings.NewReader(msg.content)
-	req, err := http.NewRequest("POST", f.url, body)
-	resp, err := f.client.dispatch(req)
+	if !f.tracker.sending(msg) {
+		f.log.Debug("message discarded, it has been cancelled", guidField, msg.guid, indexField, msg.index)
+		f.reportDiscard(msg, DiscardCancelled)
+		return
+	}
+	if msg.notification.expired(time.Now()) {
+		f.tracker.discard(msg.guid, []int{msg.index})
+		f.stats.recordExpired()
+		f.log.Warn("message discarded, it has expired", guidField, msg.guid, indexField, msg.index, attemptField, msg.numRetrials)
+		f.reportDiscard(msg, DiscardExpired)
+		return
+	}
+
+	res, err := f.deliver(context.Background(), msg)
 	if err != nil {
-		f.reportError(msg, fmt.Errorf("unable to send the request: %v", err))
+		f.log.Debug("message not delivered", guidField, msg.guid, indexField, msg.index, attemptField, msg.numRetrials, errorField, err)
+		f.tracker.notDelivered(msg)
+		f.reportError(msg, err)
 		return
 	}
+	f.tracker.delivered(msg)
+	f.log.Debug("message sent correctly", guidField, msg.guid, indexField, msg.index, attemptField, msg.numRetrials, statusField, res.Status)
+	f.reportSuccess(msg, res)
+}
 
-	// defer the close operation of the response body to avoid a resource leak
-	defer resp.Body.Close()
+// deliver hands the message to the transport and waits for the response of the receiver.
+// Each attempt creates a dispatch span, child of the enqueue span of the message. The message is redacted right
+// before, so the reports keep the original notification.
+func (f *senderHandler) deliver(ctx context.Context, msg message) (res Result, err error) {
+	ctx, span := f.tracer.Start(withParentSpan(ctx, msg.spanCtx), dispatchSpanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			guidAttribute.String(msg.guid),
+			indexAttribute.Int(msg.index),
+			attemptAttribute.Int(msg.numRetrials),
+		))
+	defer func() { endSpan(span, err) }()
 
-	// check if the response is a successful HTTP code: 200 OK or 201 Created
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		f.reportError(msg, fmt.Errorf("unexpected HTTP Status: %s", resp.Status))
-		return
+	f.mu.RLock()
+	transport, header := f.transport, f.header
+	f.mu.RUnlock()
+
+	f.metrics.InFlight(int(atomic.AddInt64(&f.inFlight, 1)))
+	start := time.Now()
+
+	res, err = transport.Deliver(ctx, Delivery{
+		GUID:         msg.guid,
+		Index:        msg.index,
+		NumRetrials:  msg.numRetrials,
+		Notification: f.redactor.redact(msg.notification, msg.numRetrials == 0).withDefaultHeader(header),
+	})
+	res.GUID = msg.guid
+
+	latency := time.Since(start)
+	f.metrics.InFlight(int(atomic.AddInt64(&f.inFlight, -1)))
+	if err != nil {
+		f.metrics.Failed(classifyFailure(res, err), latency)
+		f.stats.recordError(err)
+	} else {
+		f.metrics.Sent(latency)
 	}
-	log.Debugf("Message sent correctly: HttpCode=%s, GUID=[%s], index=%d", resp.Status, msg.guid, msg.index)
+	return res, err
+}
+
+// setTransport replaces the transport, the deliveries in progress finish with the previous one
+func (f *senderHandler) setTransport(transport Transport) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.transport = transport
 }
 
+func (f *senderHandler) setHeader(header http.Header) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.header = header
+}
+
+// reportError publishes the failure into the Error Channel. The content is redacted as it may be logged or shown,
+// the notification is kept as it is for the retrials.
 func (f *senderHandler) reportError(msg message, err error) {
 	f.errCh <- NError{
 		GUID:         msg.guid,
 		Index:        msg.index,
 		ErrorMessage: err.Error(),
-		Content:      msg.content,
+		Content:      string(f.redactor.redact(msg.notification, false).Body),
+		Notification: msg.notification,
+		NumRetrials:  msg.numRetrials,
+	}
+}
+
+func (f *senderHandler) reportSuccess(msg message, res Result) {
+	if f.successCh == nil {
+		return
+	}
+	f.successCh <- NSuccess{
+		GUID:         msg.guid,
+		Index:        msg.index,
 		NumRetrials:  msg.numRetrials,
+		StatusCode:   res.StatusCode,
+		Status:       res.Status,
+		Notification: msg.notification,
 	}
 }
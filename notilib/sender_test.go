@@ -5,8 +5,11 @@ import (
 	"context"
 	"io/ioutil"
 	"net/http"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 type MockDispatcher struct {
@@ -40,10 +43,10 @@ func TestSend(t *testing.T) {
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			var cancel context.CancelFunc
-			called := false
+			var called int32
 			mockDispatcher := &MockDispatcher{
 				dispatchMock: func(req *http.Request) (*http.Response, error) {
-					called = true
+					atomic.StoreInt32(&called, 1)
 
 					defer req.Body.Close()
 					bodyBytes, err := ioutil.ReadAll(req.Body)
@@ -61,7 +64,7 @@ func TestSend(t *testing.T) {
 				},
 			}
 			errCh := make(chan NError, 10)
-			sender := NewSender(tc.url, mockDispatcher, errCh)
+			sender := NewSender(newHTTPTransport(mustEndpoint(tc.url), mockDispatcher), nil, nil, errCh, nil, newTracker(), testInstruments)
 			ctx := context.Background()
 
 			if tc.ctxMode == contextDoneCalledBeforeSend {
@@ -75,13 +78,131 @@ func TestSend(t *testing.T) {
 			// give some time to call send method
 			time.Sleep(2 * time.Second)
 
-			if !called {
+			if atomic.LoadInt32(&called) == 0 {
 				t.Errorf("did not call client.dispatch")
 			}
 		})
 	}
 }
 
+func TestSendCancelled(t *testing.T) {
+	called := false
+	mockDispatcher := &MockDispatcher{
+		dispatchMock: func(req *http.Request) (*http.Response, error) {
+			called = true
+			return createHTTPResponse(req, ""), nil
+		},
+	}
+	tracker := newTracker()
+	msg := getDummyMessage("body content")
+	tracker.register(msg.guid, []int{msg.index}, trace.SpanContext{})
+	tracker.cancel(msg.guid, nil)
+
+	in := testInstruments
+	in.discards = make(chan NDiscard, 1)
+	sender := NewSender(newHTTPTransport(mustEndpoint("http://localhost"), mockDispatcher), nil, nil, make(chan NError, 1), nil, tracker, in)
+	sender.send(msg)
+
+	if called {
+		t.Errorf("cancelled message has been dispatched")
+	}
+	checkDiscard(t, in.discards, msg, DiscardCancelled)
+}
+
+func checkDiscard(t *testing.T, discards chan NDiscard, msg message, reason string) {
+	t.Helper()
+	select {
+	case d := <-discards:
+		if d.GUID != msg.guid || d.Index != msg.index || d.Reason != reason {
+			t.Errorf("expected %s discard of [%s][%d]; got %+v", reason, msg.guid, msg.index, d)
+		}
+	default:
+		t.Errorf("%s message not reported to the Discard Channel", reason)
+	}
+}
+
+func TestSendExpired(t *testing.T) {
+	called := false
+	mockDispatcher := &MockDispatcher{
+		dispatchMock: func(req *http.Request) (*http.Response, error) {
+			called = true
+			return createHTTPResponse(req, ""), nil
+		},
+	}
+	in := testInstruments
+	in.stats = newStatsRecorder(noopMetrics{})
+	in.discards = make(chan NDiscard, 1)
+	sender := NewSender(newHTTPTransport(mustEndpoint("http://localhost"), mockDispatcher), nil, nil, make(chan NError, 1), nil, newTracker(), in)
+
+	msg := getDummyMessage("body content")
+	msg.notification.ExpiresAt = time.Now().Add(-time.Second)
+	sender.send(msg)
+	checkDiscard(t, in.discards, msg, DiscardExpired)
+
+	if called {
+		t.Errorf("expired message has been dispatched")
+	}
+	if expired := in.stats.snapshot().Expired; expired != 1 {
+		t.Errorf("expected 1 expired message; got %d", expired)
+	}
+}
+
+func TestSendDefaultHeader(t *testing.T) {
+	received := make(chan http.Header, 2)
+	mockDispatcher := &MockDispatcher{
+		dispatchMock: func(req *http.Request) (*http.Response, error) {
+			received <- req.Header
+			return createHTTPResponse(req, ""), nil
+		},
+	}
+	header := http.Header{"authorization": {"Bearer s3cret"}, "X-Source": {"notify"}}
+	sender := NewSender(newHTTPTransport(mustEndpoint("http://localhost"), mockDispatcher), header, nil, make(chan NError, 1), nil, newTracker(), testInstruments)
+
+	msg := getDummyMessage("body content")
+	msg.notification.Header = http.Header{"X-Source": {"custom"}}
+	sender.send(msg)
+
+	got := <-received
+	if got.Get("Authorization") != "Bearer s3cret" {
+		t.Errorf("expected the default Authorization header; got: %v", got)
+	}
+	if got.Get("X-Source") != "custom" {
+		t.Errorf("expected the header of the notification to take precedence; got: %s", got.Get("X-Source"))
+	}
+	if msg.notification.Header.Get("Authorization") != "" {
+		t.Errorf("the notification has been modified")
+	}
+}
+
+func TestSendReportsSuccess(t *testing.T) {
+	mockDispatcher := &MockDispatcher{
+		dispatchMock: func(req *http.Request) (*http.Response, error) {
+			return createHTTPResponse(req, ""), nil
+		},
+	}
+	successCh := make(chan NSuccess, 1)
+	sender := NewSender(newHTTPTransport(mustEndpoint("http://localhost"), mockDispatcher), nil, nil, make(chan NError, 1), successCh, newTracker(), testInstruments)
+
+	msg := getDummyMessage("body content")
+	msg.numRetrials = 1
+	sender.send(msg)
+
+	select {
+	case s := <-successCh:
+		if s.GUID != msg.guid || s.Index != msg.index || s.NumRetrials != 1 {
+			t.Errorf("expected the delivery of [%s][%d] after 1 retrial; got [%s][%d] after %d", msg.guid, msg.index, s.GUID, s.Index, s.NumRetrials)
+		}
+		if s.StatusCode != http.StatusOK {
+			t.Errorf("expected status code %d; got %d", http.StatusOK, s.StatusCode)
+		}
+		if string(s.Notification.Body) != "body content" {
+			t.Errorf("expected the delivered notification; got %q", s.Notification.Body)
+		}
+	default:
+		t.Errorf("the delivery has not been reported")
+	}
+}
+
 func createHTTPResponse(req *http.Request, body string) *http.Response {
 	return &http.Response{
 		Proto:      "HTTP/1.1",
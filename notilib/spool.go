@@ -0,0 +1,130 @@
+package notilib
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// spooledMessage is the representation of a message in the spool file
+type spooledMessage struct {
+	GUID         string
+	Index        int
+	NumRetrials  int
+	Notification Notification
+}
+
+// spool keeps on disk, as JSON lines, the messages that do not fit into the Message Channel while the
+// delivery is paused. The file is created on the first message and removed once all of them have been read.
+type spool struct {
+	mu      sync.Mutex
+	dir     string
+	writer  *os.File
+	reader  *bufio.Reader
+	rfile   *os.File
+	pending int
+}
+
+func newSpool(dir string) *spool {
+	return &spool{dir: dir}
+}
+
+// push appends the message to the spool file
+func (s *spool) push(msg message) error {
+	line, err := json.Marshal(spooledMessage{
+		GUID:         msg.guid,
+		Index:        msg.index,
+		NumRetrials:  msg.numRetrials,
+		Notification: msg.notification,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to encode the message: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write the spool file: %v", err)
+	}
+	s.pending++
+	return nil
+}
+
+// pop reads the oldest message of the spool, it reports false if the spool is empty
+func (s *spool) pop() (message, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		return message{}, false, nil
+	}
+	line, err := s.reader.ReadBytes('\n')
+	if err != nil {
+		return message{}, false, fmt.Errorf("unable to read the spool file: %v", err)
+	}
+	s.pending--
+
+	var m spooledMessage
+	if err := json.Unmarshal(line, &m); err != nil {
+		return message{}, false, fmt.Errorf("unable to decode the spooled message: %v", err)
+	}
+	if s.pending == 0 {
+		s.remove()
+	}
+	return message{
+		notification: m.Notification,
+		guid:         m.GUID,
+		index:        m.Index,
+		numRetrials:  m.NumRetrials,
+	}, true, nil
+}
+
+// len returns the number of messages waiting in the spool
+func (s *spool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pending
+}
+
+// path returns the spool file, empty if there is none
+func (s *spool) path() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer == nil {
+		return ""
+	}
+	return s.writer.Name()
+}
+
+func (s *spool) open() error {
+	writer, err := os.CreateTemp(s.dir, "notilib-*.spool")
+	if err != nil {
+		return fmt.Errorf("unable to create the spool file: %v", err)
+	}
+	rfile, err := os.Open(writer.Name())
+	if err != nil {
+		writer.Close()
+		os.Remove(writer.Name())
+		return fmt.Errorf("unable to open the spool file: %v", err)
+	}
+	s.writer = writer
+	s.rfile = rfile
+	s.reader = bufio.NewReader(rfile)
+	return nil
+}
+
+// remove deletes the spool file once it has been read completely
+func (s *spool) remove() {
+	s.rfile.Close()
+	s.writer.Close()
+	os.Remove(s.writer.Name())
+	s.writer, s.rfile, s.reader = nil, nil, nil
+}
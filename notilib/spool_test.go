@@ -0,0 +1,48 @@
+package notilib
+
+import (
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSpool(t *testing.T) {
+	s := newSpool(t.TempDir())
+
+	if _, ok, err := s.pop(); ok || err != nil {
+		t.Fatalf("expected an empty spool; got %v, %v", ok, err)
+	}
+
+	msgs := []message{
+		{notification: Notification{Body: []byte("first")}, guid: "111-222-333-444", index: 0},
+		{notification: Notification{Body: []byte{0, 1, 2}, Header: http.Header{"X-Key": {"value"}}, Metadata: map[string]string{"id": "42"}}, guid: "111-222-333-444", index: 1, numRetrials: 2},
+	}
+	for _, msg := range msgs {
+		if err := s.push(msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	path := s.path()
+	if s.len() != len(msgs) || path == "" {
+		t.Fatalf("expected %d messages in a spool file; got %d in %q", len(msgs), s.len(), path)
+	}
+
+	for _, expected := range msgs {
+		msg, ok, err := s.pop()
+		if !ok || err != nil {
+			t.Fatalf("unexpected result: %v, %v", ok, err)
+		}
+		if !reflect.DeepEqual(msg, expected) {
+			t.Errorf("expected message: %+v; got: %+v", expected, msg)
+		}
+	}
+
+	// the file is removed once all the messages have been read
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("spool file not removed: %v", err)
+	}
+	if err := s.push(msgs[0]); err != nil || s.len() != 1 {
+		t.Errorf("unable to reuse the spool: %v", err)
+	}
+}
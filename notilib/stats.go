@@ -0,0 +1,102 @@
+package notilib
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the activity of a Notilib instance since it was created
+type Stats struct {
+	State         string    // idle, listening, draining or closed
+	Paused        bool      // the delivery has been paused
+	QueueLength   int       // number of messages waiting in the Message Channel
+	QueueCapacity int       // capacity of the Message Channel
+	Spilled       int       // messages written to disk while paused, waiting to be queued
+	InFlight      int       // number of deliveries waiting for the receiver
+	Enqueued      uint64    // messages queued into the Message Channel
+	Retried       uint64    // failed messages queued again into the Message Channel
+	Sent          uint64    // messages accepted by the receiver
+	Failed        uint64    // failed deliveries
+	Expired       uint64    // messages discarded because their Notification.ExpiresAt passed before the delivery
+	LastError     string    // error of the last failed delivery, empty if none
+	LastErrorAt   time.Time // time of the last failed delivery
+	DeadLetters   int       // notifications stored in the dead letter queue
+}
+
+// statsRecorder counts the events for Notilib.Stats and forwards them to the configured Metrics
+type statsRecorder struct {
+	next     Metrics
+	enqueued uint64
+	retried  uint64
+	sent     uint64
+	failed   uint64
+	expired  uint64
+	inFlight int64
+
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+func newStatsRecorder(next Metrics) *statsRecorder {
+	return &statsRecorder{next: next}
+}
+
+func (s *statsRecorder) Enqueued(n int) {
+	atomic.AddUint64(&s.enqueued, uint64(n))
+	s.next.Enqueued(n)
+}
+
+func (s *statsRecorder) Retried() {
+	atomic.AddUint64(&s.retried, 1)
+	s.next.Retried()
+}
+
+func (s *statsRecorder) QueueLength(n int) {
+	s.next.QueueLength(n)
+}
+
+func (s *statsRecorder) InFlight(n int) {
+	atomic.StoreInt64(&s.inFlight, int64(n))
+	s.next.InFlight(n)
+}
+
+func (s *statsRecorder) Sent(latency time.Duration) {
+	atomic.AddUint64(&s.sent, 1)
+	s.next.Sent(latency)
+}
+
+func (s *statsRecorder) Failed(class FailureClass, latency time.Duration) {
+	atomic.AddUint64(&s.failed, 1)
+	s.next.Failed(class, latency)
+}
+
+// recordExpired counts a message discarded because it expired, it is not forwarded to the Metrics
+func (s *statsRecorder) recordExpired() {
+	atomic.AddUint64(&s.expired, 1)
+}
+
+// recordError keeps the error of the last failed delivery
+func (s *statsRecorder) recordError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+}
+
+// snapshot returns the counters, the queue and state fields are filled by the caller
+func (s *statsRecorder) snapshot() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{
+		InFlight:    int(atomic.LoadInt64(&s.inFlight)),
+		Enqueued:    atomic.LoadUint64(&s.enqueued),
+		Retried:     atomic.LoadUint64(&s.retried),
+		Sent:        atomic.LoadUint64(&s.sent),
+		Failed:      atomic.LoadUint64(&s.failed),
+		Expired:     atomic.LoadUint64(&s.expired),
+		LastError:   s.lastError,
+		LastErrorAt: s.lastErrorAt,
+	}
+}
@@ -0,0 +1,31 @@
+package notilib
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStatsRecorder(t *testing.T) {
+	metrics := &MockMetrics{}
+	stats := newStatsRecorder(metrics)
+
+	stats.Enqueued(3)
+	stats.Retried()
+	stats.InFlight(2)
+	stats.Sent(time.Millisecond)
+	stats.Failed(FailureNetwork, time.Millisecond)
+	stats.recordError(fmt.Errorf("connection refused"))
+
+	snapshot := stats.snapshot()
+	expected := Stats{InFlight: 2, Enqueued: 3, Retried: 1, Sent: 1, Failed: 1, LastError: "connection refused"}
+	snapshot.LastErrorAt = time.Time{}
+	if snapshot != expected {
+		t.Errorf("expected stats: %+v; got: %+v", expected, snapshot)
+	}
+
+	// the events are forwarded to the configured metrics
+	if metrics.sent != 1 || metrics.failed[FailureNetwork] != 1 {
+		t.Errorf("events not forwarded: sent=%d failed=%v", metrics.sent, metrics.failed)
+	}
+}
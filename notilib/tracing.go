@@ -0,0 +1,48 @@
+package notilib
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans created by notilib
+const tracerName = "github.com/daniel-gil/notifications-client/notilib"
+
+// Names of the spans created by notilib
+const (
+	enqueueSpanName  = "notilib.enqueue"
+	dispatchSpanName = "notilib.dispatch"
+	retrySpanName    = "notilib.retry"
+)
+
+// Attributes of the spans created by notilib
+const (
+	guidAttribute        = attribute.Key("notilib.guid")
+	indexAttribute       = attribute.Key("notilib.index")
+	numMessagesAttribute = attribute.Key("notilib.messages")
+	attemptAttribute     = attribute.Key("notilib.attempt")
+)
+
+// traceContext propagates the span of each dispatch to the receiver using the W3C traceparent header
+var traceContext = propagation.TraceContext{}
+
+// withParentSpan returns a context whose parent span is sc, unless ctx already carries a span
+func withParentSpan(ctx context.Context, sc trace.SpanContext) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() || !sc.IsValid() {
+		return ctx
+	}
+	return trace.ContextWithSpanContext(ctx, sc)
+}
+
+// endSpan records the error, if any, and ends the span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,92 @@
+package notilib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+func TestTracing(t *testing.T) {
+	received := make(chan trace.SpanContext, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		received <- trace.SpanContextFromContext(ctx)
+	}))
+	defer server.Close()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	conf := DefaultConfig()
+	conf.TracerProvider = provider
+
+	notilib, err := New(server.URL, nil, conf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := notilib.Listen(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	guid, err := notilib.NotifyContext(ctx, []string{"hello world"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var remote trace.SpanContext
+	select {
+	case remote = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("notification not received")
+	}
+	// give some time to end the dispatch span
+	time.Sleep(100 * time.Millisecond)
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range exporter.GetSpans().Snapshots() {
+		spans[s.Name()] = s
+	}
+	enqueue, ok := spans[enqueueSpanName]
+	if !ok {
+		t.Fatalf("enqueue span not found")
+	}
+	dispatch, ok := spans[dispatchSpanName]
+	if !ok {
+		t.Fatalf("dispatch span not found")
+	}
+
+	if dispatch.Parent().SpanID() != enqueue.SpanContext().SpanID() {
+		t.Errorf("dispatch span is not a child of the enqueue span")
+	}
+	if remote.TraceID() != enqueue.SpanContext().TraceID() {
+		t.Errorf("expected trace ID in traceparent: %s; got: %s", enqueue.SpanContext().TraceID(), remote.TraceID())
+	}
+	if remote.SpanID() != dispatch.SpanContext().SpanID() {
+		t.Errorf("expected span ID in traceparent: %s; got: %s", dispatch.SpanContext().SpanID(), remote.SpanID())
+	}
+	for _, s := range []sdktrace.ReadOnlySpan{enqueue, dispatch} {
+		if !hasAttribute(s, string(guidAttribute), guid) {
+			t.Errorf("span %s without GUID attribute", s.Name())
+		}
+	}
+}
+
+func hasAttribute(s sdktrace.ReadOnlySpan, key, value string) bool {
+	for _, attr := range s.Attributes() {
+		if string(attr.Key) == key && attr.Value.AsString() == value {
+			return true
+		}
+	}
+	return false
+}
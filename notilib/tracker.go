@@ -0,0 +1,268 @@
+package notilib
+
+import (
+	"fmt"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// finishedBatchesCap is the number of finished batches kept to report them in Cancel
+const finishedBatchesCap = 1000
+
+// CancelResult summarizes the outcome of a call to Notilib.Cancel
+type CancelResult struct {
+	GUID      string // GUID: Unique identifier of the cancelled batch
+	Cancelled int    // Number of messages that will not be dispatched anymore
+	Delivered int    // Number of messages that were already delivered before the cancellation
+	InFlight  int    // Number of messages being delivered, too late to be cancelled
+	Failed    int    // Number of messages that exhausted their retrials, see Notilib.DeadLetter
+}
+
+type tracker interface {
+	register(guid string, indexes []int, sc trace.SpanContext)
+	spanContext(guid string) trace.SpanContext
+	sending(msg message) bool
+	notDelivered(msg message)
+	delivered(msg message)
+	failed(guid string, index int)
+	replayed(guid string, index int)
+	dropCancelled(msg message) bool
+	cancel(guid string, indexes []int) (CancelResult, error)
+	discard(guid string, indexes []int)
+}
+
+// batch keeps the state of every message queued under the same GUID
+type batch struct {
+	pending   map[int]bool      // messages not delivered yet
+	inFlight  map[int]bool      // pending messages waiting for the receiver, they can not be cancelled anymore
+	delivered map[int]bool      // messages already accepted by the receiver
+	failed    map[int]bool      // messages that exhausted their retrials, until they are replayed
+	cancelled map[int]bool      // messages cancelled that are still queued or waiting for a retrial
+	spanCtx   trace.SpanContext // span of the enqueue operation
+	finished  uint64            // position in the finished batches, 0 while any message can be dispatched
+}
+
+func newBatch(sc trace.SpanContext) *batch {
+	return &batch{
+		pending:   make(map[int]bool),
+		inFlight:  make(map[int]bool),
+		delivered: make(map[int]bool),
+		failed:    make(map[int]bool),
+		cancelled: make(map[int]bool),
+		spanCtx:   sc,
+	}
+}
+
+// finishedBatch identifies a batch in the order they finished
+type finishedBatch struct {
+	guid string
+	seq  uint64
+}
+
+// batchTracker keeps the batches with messages to be dispatched, and the last finishedBatchesCap finished ones
+// so Cancel reports their deliveries and failures
+type batchTracker struct {
+	mu       sync.Mutex
+	batches  map[string]*batch
+	finished []finishedBatch // from the oldest
+	seq      uint64
+	capacity int
+}
+
+func newTracker() tracker {
+	return &batchTracker{
+		batches:  make(map[string]*batch),
+		capacity: finishedBatchesCap,
+	}
+}
+
+// register starts tracking the messages queued under the given GUID
+func (t *batchTracker) register(guid string, indexes []int, sc trace.SpanContext) {
+	b := newBatch(sc)
+	for _, idx := range indexes {
+		b.pending[idx] = true
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.batches[guid] = b
+	t.release(guid, b)
+}
+
+// spanContext returns the span of the enqueue operation of the batch
+func (t *batchTracker) spanContext(guid string) trace.SpanContext {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[guid]
+	if !ok {
+		return trace.SpanContext{}
+	}
+	return b.spanCtx
+}
+
+// sending reports whether the message can be delivered, marking it in flight until notDelivered or delivered.
+// A cancelled message is not delivered and no longer tracked.
+func (t *batchTracker) sending(msg message) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[msg.guid]
+	if !ok {
+		return true
+	}
+	if b.cancelled[msg.index] {
+		delete(b.cancelled, msg.index)
+		t.release(msg.guid, b)
+		return false
+	}
+	if b.pending[msg.index] {
+		b.inFlight[msg.index] = true
+	}
+	return true
+}
+
+// notDelivered records a failed delivery, the message can be cancelled again while it waits for a retrial
+func (t *batchTracker) notDelivered(msg message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if b, ok := t.batches[msg.guid]; ok {
+		delete(b.inFlight, msg.index)
+	}
+}
+
+// delivered records that the message has reached the receiver
+func (t *batchTracker) delivered(msg message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[msg.guid]
+	if !ok || !b.pending[msg.index] {
+		return
+	}
+	delete(b.pending, msg.index)
+	delete(b.inFlight, msg.index)
+	b.delivered[msg.index] = true
+	t.release(msg.guid, b)
+}
+
+// failed records that the message exhausted its retrials
+func (t *batchTracker) failed(guid string, index int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[guid]
+	if !ok || !b.pending[index] {
+		return
+	}
+	delete(b.pending, index)
+	delete(b.inFlight, index)
+	b.failed[index] = true
+	t.release(guid, b)
+}
+
+// replayed tracks again a failed message queued from the dead letter queue
+func (t *batchTracker) replayed(guid string, index int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[guid]
+	if !ok {
+		// the batch finished long ago and it is not kept anymore
+		b = newBatch(trace.SpanContext{})
+		t.batches[guid] = b
+	}
+	delete(b.failed, index)
+	b.pending[index] = true
+	b.finished = 0
+}
+
+// dropCancelled reports whether the message has been cancelled and must not be dispatched.
+// A cancelled message is reported only once, afterwards it is no longer tracked.
+func (t *batchTracker) dropCancelled(msg message) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[msg.guid]
+	if !ok || !b.cancelled[msg.index] {
+		return false
+	}
+	delete(b.cancelled, msg.index)
+	t.release(msg.guid, b)
+	return true
+}
+
+// cancel prevents the pending messages of the batch from being dispatched, except those in flight.
+// If no indexes are provided, the whole batch is cancelled.
+func (t *batchTracker) cancel(guid string, indexes []int) (CancelResult, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[guid]
+	if !ok {
+		return CancelResult{}, fmt.Errorf("unknown GUID: %s", guid)
+	}
+
+	if len(indexes) == 0 {
+		for _, states := range []map[int]bool{b.pending, b.delivered, b.failed} {
+			for idx := range states {
+				indexes = append(indexes, idx)
+			}
+		}
+	}
+
+	res := CancelResult{GUID: guid}
+	for _, idx := range indexes {
+		switch {
+		case b.inFlight[idx]:
+			res.InFlight++
+		case b.pending[idx]:
+			delete(b.pending, idx)
+			b.cancelled[idx] = true
+			res.Cancelled++
+		case b.delivered[idx]:
+			res.Delivered++
+		case b.failed[idx]:
+			res.Failed++
+		}
+	}
+	return res, nil
+}
+
+// discard stops tracking messages that were never queued
+func (t *batchTracker) discard(guid string, indexes []int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.batches[guid]
+	if !ok {
+		return
+	}
+	for _, idx := range indexes {
+		delete(b.pending, idx)
+		delete(b.inFlight, idx)
+	}
+	t.release(guid, b)
+}
+
+// release marks the batch as finished once none of its messages can be dispatched anymore, forgetting the oldest
+// finished batch beyond the capacity
+func (t *batchTracker) release(guid string, b *batch) {
+	if len(b.pending) > 0 || len(b.cancelled) > 0 || b.finished > 0 {
+		return
+	}
+	t.seq++
+	b.finished = t.seq
+	t.finished = append(t.finished, finishedBatch{guid: guid, seq: t.seq})
+
+	for len(t.finished) > t.capacity {
+		oldest := t.finished[0]
+		t.finished = t.finished[1:]
+		// a replayed batch is removed once it finishes again
+		if b, ok := t.batches[oldest.guid]; ok && b.finished == oldest.seq {
+			delete(t.batches, oldest.guid)
+		}
+	}
+}
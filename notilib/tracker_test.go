@@ -0,0 +1,175 @@
+package notilib
+
+import (
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestCancel(t *testing.T) {
+	tt := []struct {
+		name              string
+		guid              string
+		indexes           []int
+		delivered         []int
+		expectedCancelled int
+		expectedDelivered int
+		errMsg            string
+	}{
+		{"Positive TC: whole batch", "1234", nil, []int{0}, 2, 1, ""},
+		{"Positive TC: single index", "1234", []int{2}, nil, 1, 0, ""},
+		{"Positive TC: index already delivered", "1234", []int{1}, []int{1}, 0, 1, ""},
+		{"Positive TC: unknown index", "1234", []int{7}, nil, 0, 0, ""},
+		{"Negative TC: unknown GUID", "5678", nil, nil, 0, 0, "unknown GUID: 5678"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newTracker()
+			tracker.register("1234", []int{0, 1, 2}, trace.SpanContext{})
+			for _, idx := range tc.delivered {
+				tracker.delivered(message{guid: "1234", index: idx})
+			}
+
+			res, err := tracker.cancel(tc.guid, tc.indexes)
+			if !checkError(tc.errMsg, err, t) {
+				if res.Cancelled != tc.expectedCancelled {
+					t.Errorf("unexpected number of cancelled messages: expected %d; got: %d", tc.expectedCancelled, res.Cancelled)
+				}
+				if res.Delivered != tc.expectedDelivered {
+					t.Errorf("unexpected number of delivered messages: expected %d; got: %d", tc.expectedDelivered, res.Delivered)
+				}
+			}
+		})
+	}
+}
+
+func TestDropCancelled(t *testing.T) {
+	tracker := newTracker()
+	tracker.register("1234", []int{0, 1}, trace.SpanContext{})
+	tracker.cancel("1234", []int{0})
+
+	if !tracker.dropCancelled(message{guid: "1234", index: 0}) {
+		t.Errorf("cancelled message was not dropped")
+	}
+	if tracker.dropCancelled(message{guid: "1234", index: 0}) {
+		t.Errorf("cancelled message dropped twice")
+	}
+	if tracker.dropCancelled(message{guid: "1234", index: 1}) {
+		t.Errorf("pending message was dropped")
+	}
+}
+
+func TestCancelFinishedBatch(t *testing.T) {
+	tt := []struct {
+		name              string
+		delivered         []int
+		failed            []int
+		expectedDelivered int
+		expectedFailed    int
+	}{
+		{"Positive TC: every message delivered", []int{0, 1, 2}, nil, 3, 0},
+		{"Positive TC: every message delivered or failed", []int{0, 2}, []int{1}, 2, 1},
+		{"Positive TC: every message failed", nil, []int{0, 1, 2}, 0, 3},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := newTracker()
+			tracker.register("1234", []int{0, 1, 2}, trace.SpanContext{})
+			for _, idx := range tc.delivered {
+				tracker.delivered(message{guid: "1234", index: idx})
+			}
+			for _, idx := range tc.failed {
+				tracker.failed("1234", idx)
+			}
+
+			res, err := tracker.cancel("1234", nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := CancelResult{GUID: "1234", Delivered: tc.expectedDelivered, Failed: tc.expectedFailed}
+			if res != expected {
+				t.Errorf("expected %+v; got: %+v", expected, res)
+			}
+		})
+	}
+}
+
+func TestFinishedBatchesCapacity(t *testing.T) {
+	tracker := newTracker()
+	tracker.(*batchTracker).capacity = 2
+
+	for _, guid := range []string{"1", "2", "3"} {
+		tracker.register(guid, []int{0}, trace.SpanContext{})
+		tracker.failed(guid, 0)
+	}
+	tracker.register("4", []int{0}, trace.SpanContext{})
+
+	if _, err := tracker.cancel("1", nil); err == nil {
+		t.Errorf("oldest finished batch still tracked")
+	}
+	for _, guid := range []string{"2", "3", "4"} {
+		if _, err := tracker.cancel(guid, nil); err != nil {
+			t.Errorf("batch %s no longer tracked: %v", guid, err)
+		}
+	}
+	if n := len(tracker.(*batchTracker).batches); n != 3 {
+		t.Errorf("unexpected number of batches tracked: expected 3; got: %d", n)
+	}
+}
+
+func TestCancelInFlight(t *testing.T) {
+	tracker := newTracker()
+	tracker.register("1234", []int{0, 1}, trace.SpanContext{})
+	msg := message{guid: "1234", index: 0}
+
+	if !tracker.sending(msg) {
+		t.Fatalf("pending message not sent")
+	}
+	res, _ := tracker.cancel("1234", []int{0})
+	if res.InFlight != 1 || res.Cancelled != 0 {
+		t.Errorf("message in flight cancelled: %+v", res)
+	}
+
+	// a failed delivery can be cancelled while it waits for its retrial
+	tracker.notDelivered(msg)
+	res, _ = tracker.cancel("1234", []int{0})
+	if res.Cancelled != 1 {
+		t.Errorf("message waiting for a retrial not cancelled: %+v", res)
+	}
+	if tracker.sending(msg) {
+		t.Errorf("cancelled message sent")
+	}
+
+	// the delivery of a message in flight finishes the batch
+	msg = message{guid: "1234", index: 1}
+	tracker.sending(msg)
+	tracker.cancel("1234", nil)
+	tracker.delivered(msg)
+	res, err := tracker.cancel("1234", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (CancelResult{GUID: "1234", Delivered: 1}); res != expected {
+		t.Errorf("expected %+v; got: %+v", expected, res)
+	}
+}
+
+func TestReplayed(t *testing.T) {
+	tracker := newTracker()
+	tracker.register("1234", []int{0}, trace.SpanContext{})
+	tracker.failed("1234", 0)
+	tracker.replayed("1234", 0)
+
+	res, _ := tracker.cancel("1234", nil)
+	if expected := (CancelResult{GUID: "1234", Cancelled: 1}); res != expected {
+		t.Errorf("expected %+v; got: %+v", expected, res)
+	}
+
+	// a batch no longer kept is tracked again
+	tracker.replayed("5678", 3)
+	if res, err := tracker.cancel("5678", nil); err != nil || res.Cancelled != 1 {
+		t.Errorf("replayed message not tracked: %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,88 @@
+package notilib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// Transport delivers the queued notifications to the receiver
+type Transport interface {
+	// Deliver sends the notification and waits for the receiver to accept it
+	Deliver(ctx context.Context, d Delivery) (Result, error)
+}
+
+// Delivery is a notification handed to a Transport
+type Delivery struct {
+	GUID         string       // GUID: Unique identifier
+	Index        int          // Index of the message from the slice passed as parameter to the notilib.Notify method
+	NumRetrials  int          // Current number of retrials for this notification
+	Notification Notification // Notification to be delivered
+}
+
+// newTransport chooses the transport from the scheme of the URL:
+//   - tcp://host:port for newline-delimited messages over TCP
+//   - unix:///path/to/socket for newline-delimited messages over a Unix domain socket
+//   - grpc://host:port for the gRPC NotificationSink service (see proto/sink.proto)
+//   - otherwise the URL is an HTTP endpoint
+func newTransport(url string, method string, client *http.Client) (Transport, error) {
+	scheme, addr, err := parseTransportURL(url)
+	if err != nil {
+		return nil, err
+	}
+	switch scheme {
+	case "tcp":
+		return NewTCPTransport(addr), nil
+	case "unix":
+		return NewUnixTransport(addr), nil
+	case "grpc":
+		return NewGRPCTransport(addr)
+	}
+
+	e, err := newEndpoint(url, method)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return newHTTPTransport(e, newClientHandler(client)), nil
+}
+
+// ValidateURL checks the url as New does, without connecting to the receiver: the tcp://, unix:// and grpc://
+// URLs require an address, and any other URL must be a valid text/template rendering an HTTP URL.
+func ValidateURL(url string) error {
+	scheme, _, err := parseTransportURL(url)
+	if err != nil || scheme != "" {
+		return err
+	}
+	_, err = newEndpoint(url, allowedMethods[0])
+	return err
+}
+
+// parseTransportURL returns the scheme and the address of the tcp://, unix:// and grpc:// URLs, or an empty scheme
+// for the HTTP endpoints
+func parseTransportURL(url string) (string, string, error) {
+	scheme := ""
+	if i := strings.Index(url, "://"); i > 0 {
+		scheme = strings.ToLower(url[:i])
+	}
+
+	switch scheme {
+	case "tcp", "grpc":
+		u, err := neturl.Parse(url)
+		if err != nil || u.Host == "" {
+			return "", "", fmt.Errorf("invalid URL: expected %s://host:port", scheme)
+		}
+		return scheme, u.Host, nil
+	case "unix":
+		u, err := neturl.Parse(url)
+		if err != nil || u.Path == "" {
+			return "", "", fmt.Errorf("invalid URL: expected unix:///path/to/socket")
+		}
+		return scheme, u.Path, nil
+	}
+	return "", "", nil
+}
@@ -0,0 +1,131 @@
+package notilib
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/propagation"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Metadata keys of the gRPC requests, as published in proto/sink.proto
+const (
+	sinkDeliverMethod      = "/notilib.sink.v1.NotificationSink/Deliver"
+	guidMetadataKey        = "x-notification-guid"
+	indexMetadataKey       = "x-notification-index"
+	numRetrialsMetadataKey = "x-notification-retrials"
+	contentTypeMetadataKey = "x-notification-content-type"
+)
+
+// SinkServer is the server API of the NotificationSink service defined in proto/sink.proto
+type SinkServer interface {
+	Deliver(ctx context.Context, body *wrapperspb.BytesValue) (*emptypb.Empty, error)
+}
+
+// RegisterSinkServer registers the implementation of the NotificationSink service into a gRPC server
+func RegisterSinkServer(s *grpc.Server, srv SinkServer) {
+	s.RegisterService(&sinkServiceDesc, srv)
+}
+
+var sinkServiceDesc = grpc.ServiceDesc{
+	ServiceName: "notilib.sink.v1.NotificationSink",
+	HandlerType: (*SinkServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Deliver",
+			Handler:    sinkDeliverHandler,
+		},
+	},
+	Metadata: "proto/sink.proto",
+}
+
+func sinkDeliverHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(wrapperspb.BytesValue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SinkServer).Deliver(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: sinkDeliverMethod,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SinkServer).Deliver(ctx, req.(*wrapperspb.BytesValue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+type grpcTransport struct {
+	conn *grpc.ClientConn
+}
+
+// NewGRPCTransport creates a Transport that calls the NotificationSink service at target (host:port).
+// Without dial options, the connection is established without TLS.
+func NewGRPCTransport(target string, opts ...grpc.DialOption) (Transport, error) {
+	if len(opts) == 0 {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create the gRPC client: %v", err)
+	}
+	return &grpcTransport{conn: conn}, nil
+}
+
+// Deliver calls the Deliver method of the NotificationSink service
+func (t *grpcTransport) Deliver(ctx context.Context, d Delivery) (Result, error) {
+	res := Result{GUID: d.GUID}
+
+	n := d.Notification
+	md := metadata.Pairs(
+		guidMetadataKey, d.GUID,
+		indexMetadataKey, strconv.Itoa(d.Index),
+		numRetrialsMetadataKey, strconv.Itoa(d.NumRetrials),
+	)
+	for key, values := range n.Header {
+		md.Append(strings.ToLower(key), values...)
+	}
+	if n.ContentType != "" {
+		md.Set(contentTypeMetadataKey, n.ContentType)
+	}
+	if n.CorrelationID != "" {
+		md.Set(strings.ToLower(correlationIDHeader), n.CorrelationID)
+	}
+	if n.Priority != 0 {
+		md.Set(strings.ToLower(priorityHeader), strconv.Itoa(n.Priority))
+	}
+	if n.Key != "" {
+		md.Set(strings.ToLower(keyHeader), n.Key)
+	}
+	// propagate the span of the dispatch to the receiver
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+	for key, value := range carrier {
+		md.Set(key, value)
+	}
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	err := t.conn.Invoke(ctx, sinkDeliverMethod, wrapperspb.Bytes(n.Body), &emptypb.Empty{})
+	code := grpcstatus.Code(err)
+	res.StatusCode = int(code)
+	res.Status = code.String()
+	if code != codes.OK {
+		return res, fmt.Errorf("unexpected gRPC Status: %v", err)
+	}
+	return res, nil
+}
+
+// Close closes the connection with the receiver
+func (t *grpcTransport) Close() error {
+	return t.conn.Close()
+}
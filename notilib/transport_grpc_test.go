@@ -0,0 +1,86 @@
+package notilib
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type MockSinkServer struct {
+	deliverMock func(ctx context.Context, body *wrapperspb.BytesValue) (*emptypb.Empty, error)
+}
+
+func (m *MockSinkServer) Deliver(ctx context.Context, body *wrapperspb.BytesValue) (*emptypb.Empty, error) {
+	return m.deliverMock(ctx, body)
+}
+
+func TestGRPCDeliver(t *testing.T) {
+	tt := []struct {
+		name         string
+		serverErr    error
+		expectedCode codes.Code
+		errMsg       string
+	}{
+		{"Positive TC", nil, codes.OK, ""},
+		{"Negative TC: receiver error", grpcstatus.Error(codes.Unavailable, "busy"), codes.Unavailable, "unexpected gRPC Status: rpc error: code = Unavailable desc = busy"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var body string
+			var md metadata.MD
+			address := startSinkServer(t, &MockSinkServer{
+				deliverMock: func(ctx context.Context, in *wrapperspb.BytesValue) (*emptypb.Empty, error) {
+					body = string(in.Value)
+					md, _ = metadata.FromIncomingContext(ctx)
+					return &emptypb.Empty{}, tc.serverErr
+				},
+			})
+
+			transport, err := NewGRPCTransport(address)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer transport.(*grpcTransport).Close()
+
+			res, err := transport.Deliver(context.Background(), Delivery{
+				GUID:         "1234",
+				Index:        3,
+				Notification: Notification{Body: []byte("hello world"), CorrelationID: "abc"},
+			})
+			checkError(tc.errMsg, err, t)
+			if res.StatusCode != int(tc.expectedCode) {
+				t.Errorf("expected status code: %d; got: %d", tc.expectedCode, res.StatusCode)
+			}
+			if body != "hello world" {
+				t.Errorf("expected body: hello world; got: %s", body)
+			}
+			if got := md.Get(guidMetadataKey); len(got) != 1 || got[0] != "1234" {
+				t.Errorf("expected GUID metadata: 1234; got: %v", got)
+			}
+			if got := md.Get("x-correlation-id"); len(got) != 1 || got[0] != "abc" {
+				t.Errorf("expected correlation ID metadata: abc; got: %v", got)
+			}
+		})
+	}
+}
+
+// startSinkServer starts an in-process gRPC server implementing the NotificationSink service
+func startSinkServer(t *testing.T, srv SinkServer) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	RegisterSinkServer(s, srv)
+	go s.Serve(ln)
+	t.Cleanup(s.Stop)
+	return ln.Addr().String()
+}
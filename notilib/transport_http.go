@@ -0,0 +1,93 @@
+package notilib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// maxResultBodySize limits the amount of bytes read from the response body
+const maxResultBodySize = 1 << 20
+
+type httpTransport struct {
+	target *endpoint
+	client dispatcher
+}
+
+func newHTTPTransport(target *endpoint, client dispatcher) Transport {
+	return &httpTransport{
+		target: target,
+		client: client,
+	}
+}
+
+// Deliver sends the notification as an HTTP request to the endpoint
+func (t *httpTransport) Deliver(ctx context.Context, d Delivery) (Result, error) {
+	res := Result{GUID: d.GUID}
+
+	req, err := t.newRequest(d)
+	if err != nil {
+		return res, invalidRequestError{fmt.Errorf("unable to create the request: %v", err)}
+	}
+	// propagate the span of the dispatch to the receiver
+	traceContext.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.client.dispatch(req.WithContext(ctx))
+	if err != nil {
+		return res, fmt.Errorf("unable to send the request: %v", err)
+	}
+
+	// defer the close operation of the response body to avoid a resource leak
+	defer resp.Body.Close()
+
+	res.StatusCode = resp.StatusCode
+	res.Status = resp.Status
+	res.Body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxResultBodySize))
+	if err != nil {
+		return res, fmt.Errorf("unable to read the response body: %v", err)
+	}
+
+	// check if the response is a successful HTTP code: 200 OK or 201 Created
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return res, fmt.Errorf("unexpected HTTP Status: %s", resp.Status)
+	}
+	return res, nil
+}
+
+// newRequest transforms the notification into an HTTP request sent to the endpoint
+func (t *httpTransport) newRequest(d Delivery) (*http.Request, error) {
+	n := d.Notification
+	method, target, err := t.target.resolve(d)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, target.String(), bytes.NewReader(n.Body))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range n.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if n.ContentType != "" {
+		req.Header.Set("Content-Type", n.ContentType)
+	}
+	if n.CorrelationID != "" {
+		req.Header.Set(correlationIDHeader, n.CorrelationID)
+	}
+	if n.Priority != 0 {
+		req.Header.Set(priorityHeader, strconv.Itoa(n.Priority))
+	}
+	if n.Key != "" {
+		req.Header.Set(keyHeader, n.Key)
+	}
+	return req, nil
+}
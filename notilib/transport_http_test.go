@@ -0,0 +1,91 @@
+package notilib
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHTTPDeliver(t *testing.T) {
+	tt := []struct {
+		name       string
+		statusCode int
+		errMsg     string
+	}{
+		{"Positive TC", http.StatusOK, ""},
+		{"Negative TC: unexpected status", http.StatusBadRequest, "unexpected HTTP Status: 400 Bad Request"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDispatcher := &MockDispatcher{
+				dispatchMock: func(req *http.Request) (*http.Response, error) {
+					res := createHTTPResponse(req, "response body")
+					res.StatusCode = tc.statusCode
+					res.Status = fmt.Sprintf("%d %s", tc.statusCode, http.StatusText(tc.statusCode))
+					return res, nil
+				},
+			}
+			transport := newHTTPTransport(mustEndpoint("http://localhost"), mockDispatcher)
+
+			res, err := transport.Deliver(context.Background(), Delivery{GUID: "1234", Notification: Notification{Body: []byte("body content")}})
+			checkError(tc.errMsg, err, t)
+			if res.StatusCode != tc.statusCode {
+				t.Errorf("expected status code: %d; got: %d", tc.statusCode, res.StatusCode)
+			}
+			if string(res.Body) != "response body" {
+				t.Errorf("unexpected response body: %s", res.Body)
+			}
+		})
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	tt := []struct {
+		name           string
+		notification   Notification
+		expectedMethod string
+		expectedURL    string
+		expectedHeader http.Header
+	}{
+		{"Positive TC: defaults", Notification{Body: []byte("abc")}, "POST", "http://localhost/api/notifications", http.Header{}},
+		{"Positive TC: overrides", Notification{
+			Body:          []byte(`{"a":1}`),
+			ContentType:   "application/json",
+			Header:        http.Header{"X-Custom": []string{"value"}},
+			CorrelationID: "abc-123",
+			Method:        "PUT",
+			Path:          "resources/7?force=true",
+			Priority:      5,
+			Key:           "order-7",
+		}, "PUT", "http://localhost/api/resources/7?force=true", http.Header{
+			"Content-Type":     []string{"application/json"},
+			"X-Custom":         []string{"value"},
+			"X-Correlation-Id": []string{"abc-123"},
+			"X-Priority":       []string{"5"},
+			"X-Message-Key":    []string{"order-7"},
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport := &httpTransport{target: mustEndpoint("http://localhost/api/notifications")}
+
+			req, err := transport.newRequest(Delivery{Notification: tc.notification})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if req.Method != tc.expectedMethod {
+				t.Errorf("expected method: %s; got: %s", tc.expectedMethod, req.Method)
+			}
+			if req.URL.String() != tc.expectedURL {
+				t.Errorf("expected URL: %s; got: %s", tc.expectedURL, req.URL)
+			}
+			if !reflect.DeepEqual(req.Header, tc.expectedHeader) {
+				t.Errorf("expected headers: %v; got: %v", tc.expectedHeader, req.Header)
+			}
+		})
+	}
+}
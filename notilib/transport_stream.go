@@ -0,0 +1,127 @@
+package notilib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// defaultStreamTimeout bounds the dial and write operations when the context has no deadline
+const defaultStreamTimeout = 10 * time.Second
+
+// streamTransport writes each notification body as a line into a TCP or Unix domain socket connection.
+// The receiver does not acknowledge the lines, so the delivery is at most once: a message is reported as delivered
+// once it is written into the socket buffer, and it is lost if the connection breaks before the receiver reads it.
+type streamTransport struct {
+	network string
+	address string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed <-chan struct{} // closed once the receiver closes conn, see watchClose
+}
+
+// NewTCPTransport creates a Transport that writes newline-delimited notifications to a TCP address (host:port)
+func NewTCPTransport(address string) Transport {
+	return &streamTransport{network: "tcp", address: address}
+}
+
+// NewUnixTransport creates a Transport that writes newline-delimited notifications to a Unix domain socket
+func NewUnixTransport(path string) Transport {
+	return &streamTransport{network: "unix", address: path}
+}
+
+// Deliver writes the notification body followed by a newline. Bodies containing newlines are rejected
+// because the receiver would read them as several notifications. A successful write only means that the line is
+// in the socket buffer, see streamTransport.
+func (t *streamTransport) Deliver(ctx context.Context, d Delivery) (Result, error) {
+	res := Result{GUID: d.GUID}
+	if bytes.ContainsAny(d.Notification.Body, "\r\n") {
+		return res, invalidRequestError{fmt.Errorf("unable to send the message: body contains a newline")}
+	}
+	line := append(append([]byte{}, d.Notification.Body...), '\n')
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultStreamTimeout)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// the first write to a connection closed by the receiver usually succeeds and the line is lost, so the
+	// connection is checked before. A connection broken afterwards is detected on write, and the message is
+	// written once more over a new connection.
+	if t.conn != nil && t.closedByPeer() {
+		t.conn.Close()
+		t.conn = nil
+	}
+	reused := t.conn != nil
+	err := t.write(ctx, line, deadline)
+	if err != nil && reused {
+		err = t.write(ctx, line, deadline)
+	}
+	if err != nil {
+		return res, fmt.Errorf("unable to send the message: %v", err)
+	}
+	res.Status = "written"
+	return res, nil
+}
+
+func (t *streamTransport) write(ctx context.Context, line []byte, deadline time.Time) error {
+	if t.conn == nil {
+		dialer := &net.Dialer{Deadline: deadline}
+		conn, err := dialer.DialContext(ctx, t.network, t.address)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+		t.closed = watchClose(conn)
+	}
+
+	t.conn.SetWriteDeadline(deadline)
+	if _, err := t.conn.Write(line); err != nil {
+		t.conn.Close()
+		t.conn = nil
+		return err
+	}
+	return nil
+}
+
+// closedByPeer reports whether the receiver has closed the connection
+func (t *streamTransport) closedByPeer() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// watchClose reads the connection in background, the returned channel is closed once the read fails because the
+// connection has been closed by either side or reset. The receiver does not write, anything read is discarded.
+func watchClose(conn net.Conn) <-chan struct{} {
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		io.Copy(io.Discard, conn)
+	}()
+	return closed
+}
+
+// Close closes the connection with the receiver
+func (t *streamTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		return nil
+	}
+	err := t.conn.Close()
+	t.conn = nil
+	return err
+}
@@ -0,0 +1,121 @@
+package notilib
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStreamDeliver(t *testing.T) {
+	tt := []struct {
+		name    string
+		network string
+		body    string
+		errMsg  string
+	}{
+		{"Positive TC: TCP", "tcp", "hello world", ""},
+		{"Positive TC: Unix", "unix", "hello world", ""},
+		{"Negative TC: body with newline", "tcp", "hello\nworld", "unable to send the message: body contains a newline"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			address := "127.0.0.1:0"
+			if tc.network == "unix" {
+				address = filepath.Join(t.TempDir(), "notify.sock")
+			}
+			lines, address := startLineServer(t, tc.network, address)
+
+			transport := &streamTransport{network: tc.network, address: address}
+			defer transport.Close()
+
+			// deliver twice to check that the connection is reused
+			for i := 0; i < 2; i++ {
+				_, err := transport.Deliver(context.Background(), Delivery{Notification: Notification{Body: []byte(tc.body)}})
+				if checkError(tc.errMsg, err, t) {
+					return
+				}
+
+				select {
+				case line := <-lines:
+					if line != tc.body {
+						t.Errorf("expected line: %s; got: %s", tc.body, line)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("line not received")
+				}
+			}
+		})
+	}
+}
+
+func TestStreamReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer ln.Close()
+
+	// the receiver closes every connection after reading a line
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			conn.Close()
+			lines <- line
+		}
+	}()
+
+	transport := &streamTransport{network: "tcp", address: ln.Addr().String()}
+	defer transport.Close()
+
+	for _, body := range []string{"first", "second"} {
+		if _, err := transport.Deliver(context.Background(), Delivery{Notification: Notification{Body: []byte(body)}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		select {
+		case line := <-lines:
+			if line != body+"\n" {
+				t.Errorf("expected line: %s; got: %s", body, line)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("line %s not received", body)
+		}
+		// let the transport notice that the connection has been closed
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// startLineServer listens in the given address and publishes every line read into the returned channel
+func startLineServer(t *testing.T, network, address string) (<-chan string, string) {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+	return lines, ln.Addr().String()
+}
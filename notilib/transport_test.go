@@ -0,0 +1,59 @@
+package notilib
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewTransport(t *testing.T) {
+	tt := []struct {
+		name         string
+		url          string
+		expectedType string
+		errMsg       string
+	}{
+		{"Positive TC: HTTP", "http://localhost/api", "*notilib.httpTransport", ""},
+		{"Positive TC: TCP", "tcp://localhost:9000", "*notilib.streamTransport", ""},
+		{"Positive TC: Unix", "unix:///run/notify.sock", "*notilib.streamTransport", ""},
+		{"Positive TC: gRPC", "grpc://localhost:9000", "*notilib.grpcTransport", ""},
+		{"Missing TCP address", "tcp://", "", "invalid URL: expected tcp://host:port"},
+		{"Missing Unix socket path", "unix://", "", "invalid URL: expected unix:///path/to/socket"},
+		{"Invalid URL", "http/abc", "", "invalid URL"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			transport, err := newTransport(tc.url, "POST", nil)
+			if !checkError(tc.errMsg, err, t) {
+				if fmt.Sprintf("%T", transport) != tc.expectedType {
+					t.Errorf("expected transport: %s; got: %T", tc.expectedType, transport)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tt := []struct {
+		name   string
+		url    string
+		errMsg string
+	}{
+		{"Positive TC: HTTP", "http://localhost/api", ""},
+		{"Positive TC: template", "http://localhost/api/{{.Metadata.id | path}}", ""},
+		{"Positive TC: gRPC", "grpc://localhost:9000", ""},
+		{"Missing gRPC address", "grpc://", "invalid URL: expected grpc://host:port"},
+		{"Missing Unix socket path", "unix://", "invalid URL: expected unix:///path/to/socket"},
+		{"Invalid URL", "http/abc", "invalid URL"},
+		{"Invalid template", "http://localhost/{{.Metadata.id", "invalid URL template: template: url:1: unclosed action"},
+		{"Empty URL", "", "empty URL"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if !checkError(tc.errMsg, ValidateURL(tc.url), t) && tc.errMsg != "" {
+				t.Errorf("expected error message: %s; got no error", tc.errMsg)
+			}
+		})
+	}
+}
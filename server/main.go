@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var rnd *rand.Rand
@@ -31,6 +33,9 @@ func main() {
 			return
 		}
 
+		// log the trace context propagated by notilib in the traceparent header
+		logTraceContext(c.Request)
+
 		// check if we have to force an error
 		if *errorRatePercentage == 0 {
 			c.String(http.StatusOK, string(body))
@@ -46,6 +51,14 @@ func main() {
 	engine.Run(port())
 }
 
+func logTraceContext(req *http.Request) {
+	ctx := propagation.TraceContext{}.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+	sc := trace.SpanContextFromContext(ctx)
+	if sc.IsValid() {
+		log.Printf("Trace context received: trace_id=%s span_id=%s", sc.TraceID(), sc.SpanID())
+	}
+}
+
 func init() {
 	src := rand.NewSource(time.Now().UnixNano())
 	rnd = rand.New(src)